@@ -0,0 +1,299 @@
+// Package client is the Go SDK for the ccanalytics API.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Version = "0.1.0"
+
+	// MaxCheckBatchSize mirrors the server limit on digests per check request
+	MaxCheckBatchSize = 100
+
+	// RequestIDHeader is the header the API reads and echoes back the request ID from
+	RequestIDHeader = "X-Request-ID"
+
+	defaultTimeout     = 30 * time.Second
+	defaultMaxRetries  = 2
+	defaultBaseBackoff = 250 * time.Millisecond
+	maxBackoff         = 5 * time.Second
+)
+
+var (
+	ErrInvalidBaseURL = errors.New("invalid base url")
+)
+
+// APIError is returned whenever the API answers with a non successful status code.
+type APIError struct {
+	StatusCode int
+	Message    string
+	RequestID  string
+}
+
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("ccanalytics api returned %d: %s (request id %s)", e.StatusCode, e.Message, e.RequestID)
+	}
+
+	return fmt.Sprintf("ccanalytics api returned %d: %s", e.StatusCode, e.Message)
+}
+
+type Client struct {
+	baseURL     *url.URL
+	token       string
+	userAgent   string
+	httpClient  *http.Client
+	timeout     time.Duration
+	maxRetries  int
+	baseBackoff time.Duration
+}
+
+type Option func(*Client)
+
+// WithToken authenticates every request with the given bearer token.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout bounds every call, retries included. Zero disables it and leaves the deadline to the caller's context.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetry sets how many times a call is retried after a 5xx or a transport error,
+// waiting baseBackoff, then twice that and so on between attempts. Only calls that change nothing on the server
+// are retried, signing, revoking and file uploads are sent once.
+func WithRetry(maxRetries int, baseBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.baseBackoff = baseBackoff
+	}
+}
+
+// WithUserAgent identifies the caller, it defaults to UserAgent("ccanalytics-go/<Version>").
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%q: %w", baseURL, ErrInvalidBaseURL)
+	}
+
+	c := &Client{
+		baseURL:     u,
+		userAgent:   UserAgent("ccanalytics-go/" + Version),
+		httpClient:  &http.Client{},
+		timeout:     defaultTimeout,
+		maxRetries:  defaultMaxRetries,
+		baseBackoff: defaultBaseBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Sign streams the file up for signing, filename is sent as the original filename. It is never retried, the file
+// may already have been signed when the connection dropped.
+func (c *Client) Sign(ctx context.Context, filename string, file io.Reader, metadata Metadata) (*SignedBinary, error) {
+	body, contentType := multipartBody(filename, file, func(form *multipart.Writer) error {
+		fields := map[string]string{
+			"artifact_name": metadata.ArtifactName,
+			"version":       metadata.Version,
+			"os":            metadata.OS,
+			"arch":          metadata.Arch,
+			"uploader":      metadata.Uploader,
+		}
+		for name, value := range fields {
+			if value == "" {
+				continue
+			}
+
+			if err := form.WriteField(name, value); err != nil {
+				return err
+			}
+		}
+
+		for key, value := range metadata.Labels {
+			if err := form.WriteField("label", key+"="+value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	var signedBinary SignedBinary
+	req := request{method: http.MethodPost, path: "/binsign/sign", contentType: contentType, stream: body}
+	if err := c.do(ctx, req, &signedBinary); err != nil {
+		return nil, err
+	}
+
+	return &signedBinary, nil
+}
+
+// CheckFile uploads the whole file to be checked, prefer CheckDigest unless the file is already in memory.
+// Unsigned, revoked, untrusted and legacy files are reported through the result status rather than as errors.
+func (c *Client) CheckFile(ctx context.Context, filename string, file io.Reader) (*FileCheckResult, error) {
+	body, contentType := multipartBody(filename, file, nil)
+
+	res, err := c.send(ctx, request{method: http.MethodPost, path: "/binsign/checksign", contentType: contentType, stream: body})
+	if err != nil {
+		return nil, err
+	}
+
+	switch res.statusCode {
+	case http.StatusNotFound:
+		return &FileCheckResult{Status: StatusUnsigned, Filename: filename}, nil
+	case http.StatusOK, http.StatusGone, http.StatusForbidden:
+		var payload struct {
+			Status   Status `json:"status"`
+			Filename string `json:"file_name"`
+			SignedBinary
+		}
+		err := json.Unmarshal(res.body, &payload)
+
+		// a 403 from anything in front of the API rather than an untrusted signature
+		if res.statusCode == http.StatusForbidden && (err != nil || payload.Status != StatusUntrusted) {
+			return nil, newAPIError(res)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &FileCheckResult{Status: payload.Status, Filename: payload.Filename, SignedBinary: &payload.SignedBinary}, nil
+	default:
+		return nil, newAPIError(res)
+	}
+}
+
+// CheckDigests checks up to MaxCheckBatchSize digests at once, the file itself never leaves the machine.
+func (c *Client) CheckDigests(ctx context.Context, items []CheckItem) ([]CheckResult, error) {
+	body, err := json.Marshal(checkRequest{Items: items})
+	if err != nil {
+		return nil, err
+	}
+
+	var response checkResponse
+	// checking changes nothing on the server, it is safe to send again
+	req := request{method: http.MethodPost, path: "/binsign/check", contentType: "application/json", body: body, idempotent: true}
+	if err := c.do(ctx, req, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Results, nil
+}
+
+// CheckDigest checks a single hex encoded sha256 digest.
+func (c *Client) CheckDigest(ctx context.Context, digest string) (*CheckResult, error) {
+	results, err := c.CheckDigests(ctx, []CheckItem{{Digest: digest, Algorithm: DigestAlgorithmSHA256}})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) != 1 {
+		return nil, fmt.Errorf("expected 1 check result, got %d", len(results))
+	}
+
+	return &results[0], nil
+}
+
+// Get returns the signed binary with the given hex encoded sha256 digest, or nil when it was never signed.
+func (c *Client) Get(ctx context.Context, digest string) (*SignedBinary, error) {
+	var signedBinary SignedBinary
+
+	err := c.do(ctx, request{method: http.MethodGet, path: "/binsign/binaries/" + url.PathEscape(digest)}, &signedBinary)
+	if apiErr, ok := errors.AsType[*APIError](err); ok && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &signedBinary, nil
+}
+
+// List returns one page of signed binaries, pass the returned NextCursor back in opts.Cursor to get the next one.
+func (c *Client) List(ctx context.Context, opts ListOptions) (*Page, error) {
+	var page Page
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/binsign/binaries", query: opts.query()}, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// Revoke marks the signature of the given digest as no longer trusted, it requires a token.
+func (c *Client) Revoke(ctx context.Context, digest string, reason RevocationReason) (*Revocation, error) {
+	body, err := json.Marshal(revokeRequest{Hash: digest, Reason: reason})
+	if err != nil {
+		return nil, err
+	}
+
+	var revocation Revocation
+	req := request{method: http.MethodPost, path: "/binsign/revoke", contentType: "application/json", body: body}
+	if err := c.do(ctx, req, &revocation); err != nil {
+		return nil, err
+	}
+
+	return &revocation, nil
+}
+
+// multipartBody streams the form as it is read, the file is never held in memory as a whole. The form is written
+// by a goroutine that stops as soon as the reader is closed, which the http client does once it is done sending.
+func multipartBody(filename string, file io.Reader, writeFields func(*multipart.Writer) error) (io.ReadCloser, string) {
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	go func() {
+		writer.CloseWithError(writeForm(form, filename, file, writeFields))
+	}()
+
+	return reader, form.FormDataContentType()
+}
+
+func writeForm(form *multipart.Writer, filename string, file io.Reader, writeFields func(*multipart.Writer) error) error {
+	if writeFields != nil {
+		if err := writeFields(form); err != nil {
+			return err
+		}
+	}
+
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+
+	return form.Close()
+}
@@ -0,0 +1,537 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/client"
+	"github.com/Gustrb/ccanalytics/internal/analytics"
+	"github.com/Gustrb/ccanalytics/internal/binsign"
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/Gustrb/ccanalytics/internal/events"
+	"github.com/Gustrb/ccanalytics/internal/handlers"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database/migrator"
+	"github.com/Gustrb/ccanalytics/internal/keys"
+	"github.com/Gustrb/ccanalytics/internal/rest"
+	"github.com/Gustrb/ccanalytics/internal/rest/location"
+	"github.com/Gustrb/ccanalytics/internal/rest/useragent"
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "test-token"
+
+var api http.Handler
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	ctx := context.Background()
+
+	dir, err := os.MkdirTemp("", "ccanalytics-client-test")
+	if err != nil {
+		slog.Error("Failed to create temp dir", "error", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	cleanup, err := database.Connect(ctx, config.DatabaseConfig{
+		Path:        filepath.Join(dir, "app.db"),
+		JournalMode: "wal",
+		ForeignKeys: true,
+	})
+	if err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		return 1
+	}
+	defer cleanup()
+
+	if err := migrator.MigrateUp(ctx); err != nil {
+		slog.Error("Failed to migrate database", "error", err)
+		return 1
+	}
+
+	key, err := keys.Generate(ctx)
+	if err != nil {
+		slog.Error("Failed to generate key", "error", err)
+		return 1
+	}
+
+	if _, err := keys.Activate(ctx, key.Fingerprint); err != nil {
+		slog.Error("Failed to activate key", "error", err)
+		return 1
+	}
+
+	config.Rest.AuthToken = testToken
+
+	locations, err := location.NewChain(config.Location)
+	if err != nil {
+		slog.Error("Failed to set up location resolvers", "error", err)
+		return 1
+	}
+	defer locations.Close()
+
+	e := echo.New()
+	e.Use(rest.WithTransaction)
+	e.Use(rest.WithRequestID)
+	e.Use(rest.WithLocation(locations))
+	e.Use(rest.WithUserAgent)
+	e.Use(rest.WithLogging)
+	handlers.Register(e)
+
+	api = e
+
+	return m.Run()
+}
+
+func newTestClient(t *testing.T, handler http.Handler, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(server.URL, append([]client.Option{client.WithRetry(2, time.Millisecond)}, opts...)...)
+	require.NoError(t, err)
+
+	return c
+}
+
+func randomFile(t *testing.T) ([]byte, string) {
+	t.Helper()
+
+	data := make([]byte, 256)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+
+	sum := sha256.Sum256(data)
+
+	return data, hex.EncodeToString(sum[:])
+}
+
+func TestShouldSignAndCheckFiles(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, api, client.WithToken(testToken))
+
+	data, digest := randomFile(t)
+
+	signed, err := c.Sign(ctx, "tool.bin", bytesReader(data), client.Metadata{
+		ArtifactName: "sign-and-check",
+		Version:      "1.0.0",
+		Labels:       map[string]string{"channel": "stable"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, digest, signed.Hash)
+	require.Equal(t, "tool.bin", signed.Filename)
+	require.Equal(t, "stable", signed.Labels["channel"])
+
+	_, err = c.Sign(ctx, "tool.bin", bytesReader(data), client.Metadata{})
+	apiErr, ok := errors.AsType[*client.APIError](err)
+	require.True(t, ok)
+	require.Equal(t, http.StatusConflict, apiErr.StatusCode)
+
+	result, err := c.CheckDigest(ctx, digest)
+	require.NoError(t, err)
+	require.Equal(t, client.StatusSigned, result.Status)
+	require.Equal(t, signed.Signature, result.SignedBinary.Signature)
+
+	fileResult, err := c.CheckFile(ctx, "tool.bin", bytesReader(data))
+	require.NoError(t, err)
+	require.Equal(t, client.StatusSigned, fileResult.Status)
+	require.Equal(t, "1.0.0", fileResult.SignedBinary.Version)
+
+	got, err := c.Get(ctx, digest)
+	require.NoError(t, err)
+	require.Equal(t, signed.Signature, got.Signature)
+
+	got, err = c.Get(ctx, strings.ToUpper(digest))
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	require.Equal(t, signed.Signature, got.Signature)
+
+	unknown, unknownDigest := randomFile(t)
+
+	got, err = c.Get(ctx, unknownDigest)
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	fileResult, err = c.CheckFile(ctx, "unknown.bin", bytesReader(unknown))
+	require.NoError(t, err)
+	require.Equal(t, client.StatusUnsigned, fileResult.Status)
+	require.Nil(t, fileResult.SignedBinary)
+}
+
+func TestShouldProveInclusionWhateverTheCaseOfTheHash(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, api, client.WithToken(testToken))
+
+	data, digest := randomFile(t)
+
+	_, err := c.Sign(ctx, "tool.bin", bytesReader(data), client.Metadata{})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tlog/proof/inclusion?hash="+strings.ToUpper(digest), nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), digest)
+}
+
+func TestShouldRequireTokenToRevoke(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, api, client.WithToken(testToken))
+	anonymous := newTestClient(t, api)
+
+	data, digest := randomFile(t)
+
+	_, err := c.Sign(ctx, "tool.bin", bytesReader(data), client.Metadata{})
+	require.NoError(t, err)
+
+	_, err = anonymous.Revoke(ctx, digest, client.RevocationReasonSuperseded)
+	apiErr, ok := errors.AsType[*client.APIError](err)
+	require.True(t, ok)
+	require.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+
+	revocation, err := c.Revoke(ctx, digest, client.RevocationReasonSuperseded)
+	require.NoError(t, err)
+	require.Equal(t, client.RevocationReasonSuperseded, revocation.RevocationReason)
+
+	result, err := anonymous.CheckDigest(ctx, digest)
+	require.NoError(t, err)
+	require.Equal(t, client.StatusRevoked, result.Status)
+
+	fileResult, err := anonymous.CheckFile(ctx, "tool.bin", bytesReader(data))
+	require.NoError(t, err)
+	require.Equal(t, client.StatusRevoked, fileResult.Status)
+	require.Equal(t, "superseded", fileResult.SignedBinary.RevocationReason)
+}
+
+func TestShouldNotTrustFilesSignedByACompromisedKey(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, api, client.WithToken(testToken))
+
+	data, digest := randomFile(t)
+
+	signed, err := c.Sign(ctx, "tool.bin", bytesReader(data), client.Metadata{})
+	require.NoError(t, err)
+
+	_, err = keys.Rotate(ctx)
+	require.NoError(t, err)
+
+	_, err = keys.Retire(ctx, signed.KeyID, true)
+	require.NoError(t, err)
+
+	result, err := c.CheckDigest(ctx, digest)
+	require.NoError(t, err)
+	require.Equal(t, client.StatusUntrusted, result.Status)
+	require.Equal(t, "compromised", result.SignedBinary.KeyStatus)
+
+	fileResult, err := c.CheckFile(ctx, "tool.bin", bytesReader(data))
+	require.NoError(t, err)
+	require.Equal(t, client.StatusUntrusted, fileResult.Status)
+	require.Equal(t, signed.KeyID, fileResult.SignedBinary.KeyID)
+}
+
+func TestShouldReportFilesRegisteredBeforeSignaturesAsLegacy(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, api)
+
+	data, digest := randomFile(t)
+
+	// what the baseline wrote, the signature and key columns were added after it
+	_, err := database.ExecContext(ctx, "insert into signed_binaries (hash, created_at, updated_at) values (?, ?, ?);", digest, 1, 1)
+	require.NoError(t, err)
+
+	result, err := c.CheckDigest(ctx, digest)
+	require.NoError(t, err)
+	require.Empty(t, result.Error)
+	require.Equal(t, client.StatusLegacy, result.Status)
+
+	fileResult, err := c.CheckFile(ctx, "tool.bin", bytesReader(data))
+	require.NoError(t, err)
+	require.Equal(t, client.StatusLegacy, fileResult.Status)
+	require.Empty(t, fileResult.SignedBinary.KeyID)
+}
+
+func TestShouldPaginateList(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, api, client.WithToken(testToken))
+
+	for range 3 {
+		data, _ := randomFile(t)
+
+		_, err := c.Sign(ctx, "tool.bin", bytesReader(data), client.Metadata{ArtifactName: "paginated"})
+		require.NoError(t, err)
+	}
+
+	opts := client.ListOptions{ArtifactName: "paginated", Limit: 2}
+
+	first, err := c.List(ctx, opts)
+	require.NoError(t, err)
+	require.Len(t, first.Binaries, 2)
+	require.NotEmpty(t, first.NextCursor)
+
+	opts.Cursor = first.NextCursor
+
+	second, err := c.List(ctx, opts)
+	require.NoError(t, err)
+	require.Len(t, second.Binaries, 1)
+	require.Empty(t, second.NextCursor)
+}
+
+func TestShouldSignWhileEventsAreRolledUp(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, api, client.WithToken(testToken))
+
+	writer := events.Start(config.EventsConfig{BufferSize: 100, BatchSize: 1, FlushInterval: time.Millisecond})
+
+	stop := make(chan struct{})
+	rolledUp := make(chan error, 1)
+
+	go func() {
+		for {
+			select {
+			case <-stop:
+				rolledUp <- nil
+				return
+			default:
+			}
+
+			if _, err := analytics.RollUp(ctx, 1); err != nil {
+				rolledUp <- err
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+
+	for range cap(errs) {
+		wg.Go(func() {
+			data, _ := randomFile(t)
+
+			_, err := c.Sign(ctx, "tool.bin", bytesReader(data), client.Metadata{ArtifactName: "while-rolling-up"})
+			errs <- err
+		})
+	}
+
+	wg.Wait()
+	close(errs)
+	close(stop)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, <-rolledUp)
+	require.NoError(t, writer.Close(ctx))
+}
+
+func TestShouldRunRequestsThatGotBusyAgain(t *testing.T) {
+	ctx := context.Background()
+	_, digest := randomFile(t)
+
+	e := echo.New()
+	e.Use(rest.WithTransaction)
+
+	attempts := 0
+	e.POST("/register", func(c *echo.Context) error {
+		attempts++
+
+		var req struct {
+			Hash string `json:"hash"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+		}
+
+		ctx := c.Request().Context()
+
+		if _, err := binsign.GetSignedBinaryByHash(ctx, req.Hash); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get signed binary")
+		}
+
+		if attempts == 1 {
+			// committed after our read, so the write below gets busy no matter how long it would wait
+			_, err := database.ExecContext(context.Background(), "insert into signed_binaries (hash, created_at, updated_at) values (?, ?, ?);", "busy-"+req.Hash, 1, 1)
+			require.NoError(t, err)
+		}
+
+		if _, err := database.ExecContext(ctx, "insert into signed_binaries (hash, created_at, updated_at) values (?, ?, ?);", req.Hash, 1, 1); err != nil {
+			// what the handlers do, the busy error never makes it out of here
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to register binary")
+		}
+
+		return c.JSON(http.StatusCreated, req)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(`{"hash":"`+digest+`"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Equal(t, 2, attempts)
+	require.Contains(t, rec.Body.String(), digest)
+
+	registered, err := binsign.GetSignedBinaryByHash(ctx, digest)
+	require.NoError(t, err)
+	require.NotNil(t, registered)
+}
+
+func TestShouldRetryServerErrors(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	flaky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		api.ServeHTTP(w, r)
+	})
+
+	c := newTestClient(t, flaky)
+
+	_, digest := randomFile(t)
+
+	result, err := c.CheckDigest(ctx, digest)
+	require.NoError(t, err)
+	require.Equal(t, client.StatusUnsigned, result.Status)
+	require.EqualValues(t, 3, attempts.Load())
+
+	attempts.Store(0)
+
+	noRetries := newTestClient(t, flaky, client.WithRetry(0, 0))
+
+	_, err = noRetries.CheckDigest(ctx, digest)
+	apiErr, ok := errors.AsType[*client.APIError](err)
+	require.True(t, ok)
+	require.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	require.EqualValues(t, 1, attempts.Load())
+}
+
+func TestShouldNotRetryCallsThatChangeTheServer(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	failing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		// the server may have signed or revoked before the answer got lost
+		api.ServeHTTP(httptest.NewRecorder(), r)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	c := newTestClient(t, failing, client.WithToken(testToken))
+
+	data, digest := randomFile(t)
+
+	_, err := c.Sign(ctx, "tool.bin", bytesReader(data), client.Metadata{})
+	apiErr, ok := errors.AsType[*client.APIError](err)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	require.EqualValues(t, 1, attempts.Load())
+
+	attempts.Store(0)
+
+	_, err = c.Revoke(ctx, digest, client.RevocationReasonSuperseded)
+	require.Error(t, err)
+	require.EqualValues(t, 1, attempts.Load())
+
+	attempts.Store(0)
+
+	_, err = c.CheckFile(ctx, "tool.bin", bytesReader(data))
+	require.Error(t, err)
+	require.EqualValues(t, 1, attempts.Load())
+
+	// it did go through, once
+	result, err := newTestClient(t, api).CheckDigest(ctx, digest)
+	require.NoError(t, err)
+	require.Equal(t, client.StatusRevoked, result.Status)
+}
+
+func TestShouldNotRetryClientErrors(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	counting := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		api.ServeHTTP(w, r)
+	})
+
+	c := newTestClient(t, counting)
+
+	_, digest := randomFile(t)
+
+	_, err := c.Revoke(ctx, digest, client.RevocationReasonSuperseded)
+	require.Error(t, err)
+	require.EqualValues(t, 1, attempts.Load())
+}
+
+func TestShouldPropagateRequestID(t *testing.T) {
+	var received atomic.Value
+	recording := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Store(r.Header.Get(client.RequestIDHeader))
+		api.ServeHTTP(w, r)
+	})
+
+	c := newTestClient(t, recording, client.WithToken(testToken))
+
+	_, digest := randomFile(t)
+	ctx := client.WithRequestID(context.Background(), "req-1234")
+
+	_, err := c.Revoke(ctx, digest, client.RevocationReasonSuperseded)
+	apiErr, ok := errors.AsType[*client.APIError](err)
+	require.True(t, ok)
+	require.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	require.Equal(t, "req-1234", apiErr.RequestID)
+	require.Equal(t, "req-1234", received.Load())
+}
+
+func TestShouldTimeOut(t *testing.T) {
+	release := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+
+	c := newTestClient(t, slow, client.WithTimeout(20*time.Millisecond))
+
+	// registered after the server, so it runs before the server waits on the handler
+	t.Cleanup(func() { close(release) })
+
+	_, err := c.CheckDigest(context.Background(), fmt.Sprintf("%064d", 0))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func bytesReader(data []byte) *bytes.Reader {
+	return bytes.NewReader(data)
+}
+
+func TestShouldTellTheAPIWhereTheClientRuns(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+
+	parsed := useragent.Parse(client.UserAgent("ccanalytics-cli/"+client.Version, "checksign"))
+	require.Equal(t, useragent.KindCLI, parsed.Kind)
+	require.Equal(t, client.Version, parsed.Version)
+	require.Equal(t, "checksign", parsed.Command)
+	require.Equal(t, "github-actions", parsed.CI)
+	require.NotEmpty(t, parsed.OS)
+}
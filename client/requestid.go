@@ -0,0 +1,16 @@
+package client
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID makes every call made with ctx carry the given request ID, so the API logs it under the same ID
+// as the caller. Services handling an API request should pass their own request ID along.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
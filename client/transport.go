@@ -0,0 +1,162 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	contentType string
+	body        []byte
+	// stream is sent instead of body when set, it can only be read once so the request is never retried
+	stream io.ReadCloser
+	// idempotent requests can be sent again without changing the outcome, GETs always are
+	idempotent bool
+}
+
+func (r request) retryable() bool {
+	return r.stream == nil && (r.idempotent || r.method == http.MethodGet)
+}
+
+type response struct {
+	statusCode int
+	requestID  string
+	body       []byte
+}
+
+// do sends req and decodes a successful response into out, any other status is returned as an *APIError.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	res, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if res.statusCode < 200 || res.statusCode >= 300 {
+		return newAPIError(res)
+	}
+
+	if out == nil || len(res.body) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(res.body, out)
+}
+
+// send performs req, retrying idempotent requests with exponential backoff while the API answers with a 5xx or
+// cannot be reached. Anything else could have gone through before the connection dropped, so it is sent once.
+func (c *Client) send(ctx context.Context, req request) (*response, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := c.sendOnce(ctx, req)
+		if attempt >= c.maxRetries || !req.retryable() || !shouldRetry(res, err) || ctx.Err() != nil {
+			return res, err
+		}
+
+		select {
+		case <-ctx.Done():
+			// the last answer explains the failure better than the deadline does
+			return res, err
+		case <-time.After(c.backoff(attempt)):
+		}
+	}
+}
+
+func (c *Client) sendOnce(ctx context.Context, req request) (*response, error) {
+	u := c.baseURL.JoinPath(req.path)
+	u.RawQuery = req.query.Encode()
+
+	var reqBody io.Reader = bytes.NewReader(req.body)
+	if req.stream != nil {
+		reqBody = req.stream
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), reqBody)
+	if err != nil {
+		if req.stream != nil {
+			req.stream.Close()
+		}
+
+		return nil, err
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		httpReq.Header.Set(RequestIDHeader, requestID)
+	}
+
+	res, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response{
+		statusCode: res.StatusCode,
+		requestID:  res.Header.Get(RequestIDHeader),
+		body:       body,
+	}, nil
+}
+
+func shouldRetry(res *response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return res.statusCode >= 500
+}
+
+// backoff doubles the wait on every attempt, with up to 50% jitter so clients failing together don't retry together.
+func (c *Client) backoff(attempt int) time.Duration {
+	if c.baseBackoff <= 0 {
+		return 0
+	}
+
+	wait := maxBackoff
+	if attempt < 16 {
+		wait = min(c.baseBackoff<<attempt, maxBackoff)
+	}
+
+	return wait/2 + rand.N(wait/2+1)
+}
+
+func newAPIError(res *response) *APIError {
+	var payload struct {
+		Message string `json:"message"`
+	}
+
+	message := http.StatusText(res.statusCode)
+	if err := json.Unmarshal(res.body, &payload); err == nil && payload.Message != "" {
+		message = payload.Message
+	}
+
+	return &APIError{StatusCode: res.statusCode, Message: message, RequestID: res.requestID}
+}
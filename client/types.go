@@ -0,0 +1,169 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Status is the outcome of checking a binary.
+type Status string
+
+const (
+	StatusSigned   Status = "signed"
+	StatusUnsigned Status = "unsigned"
+	StatusRevoked  Status = "revoked"
+	// StatusUntrusted files were signed by a key that was since flagged as compromised
+	StatusUntrusted Status = "untrusted"
+	// StatusLegacy files were registered before signatures were recorded, they carry no signature to verify
+	StatusLegacy Status = "legacy"
+)
+
+const (
+	DigestAlgorithmSHA256 = "sha256"
+)
+
+// SignedBinary mirrors the signed binary representation returned by the API.
+type SignedBinary struct {
+	Hash             string            `json:"hash"`
+	Algorithm        string            `json:"algorithm"`
+	Signature        string            `json:"signature"`
+	KeyID            string            `json:"key_id"`
+	KeyStatus        string            `json:"key_status"`
+	SignedAt         int64             `json:"signed_at"`
+	ArtifactName     string            `json:"artifact_name"`
+	Version          string            `json:"version"`
+	OS               string            `json:"os"`
+	Arch             string            `json:"arch"`
+	Filename         string            `json:"original_filename"`
+	Size             int64             `json:"size"`
+	Uploader         string            `json:"uploader"`
+	Labels           map[string]string `json:"labels"`
+	RevokedAt        int64             `json:"revoked_at,omitempty"`
+	RevocationReason string            `json:"revocation_reason,omitempty"`
+}
+
+// Metadata describes the release a file being signed belongs to, every field is optional.
+type Metadata struct {
+	ArtifactName string
+	Version      string
+	OS           string
+	Arch         string
+	Uploader     string
+	Labels       map[string]string
+}
+
+// FileCheckResult is the outcome of CheckFile, SignedBinary is nil when the file is unsigned.
+type FileCheckResult struct {
+	Status       Status
+	Filename     string
+	SignedBinary *SignedBinary
+}
+
+type RevocationReason string
+
+const (
+	RevocationReasonUnspecified          RevocationReason = "unspecified"
+	RevocationReasonKeyCompromise        RevocationReason = "key_compromise"
+	RevocationReasonSuperseded           RevocationReason = "superseded"
+	RevocationReasonCessationOfOperation RevocationReason = "cessation_of_operation"
+	RevocationReasonCompromisedBuild     RevocationReason = "compromised_build"
+	RevocationReasonSignedInError        RevocationReason = "signed_in_error"
+)
+
+type Revocation struct {
+	Hash             string           `json:"hash"`
+	RevokedAt        int64            `json:"revoked_at"`
+	RevocationReason RevocationReason `json:"revocation_reason"`
+}
+
+type revokeRequest struct {
+	Hash   string           `json:"hash"`
+	Reason RevocationReason `json:"reason"`
+}
+
+// ListOptions filters List, zero values are left out of the query.
+type ListOptions struct {
+	ArtifactName  string
+	Version       string
+	OS            string
+	Arch          string
+	Filename      string
+	Uploader      string
+	Labels        map[string]string
+	HashPrefix    string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Cursor        string
+	Limit         int
+	// Order is either "asc" or "desc", the API defaults to newest first
+	Order string
+}
+
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+
+	fields := map[string]string{
+		"artifact_name":     o.ArtifactName,
+		"version":           o.Version,
+		"os":                o.OS,
+		"arch":              o.Arch,
+		"original_filename": o.Filename,
+		"uploader":          o.Uploader,
+		"hash_prefix":       o.HashPrefix,
+		"cursor":            o.Cursor,
+		"order":             o.Order,
+	}
+	for name, value := range fields {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	for key, value := range o.Labels {
+		query.Add("label", key+"="+value)
+	}
+
+	if !o.CreatedAfter.IsZero() {
+		query.Set("created_after", strconv.FormatInt(o.CreatedAfter.UnixNano(), 10))
+	}
+
+	if !o.CreatedBefore.IsZero() {
+		query.Set("created_before", strconv.FormatInt(o.CreatedBefore.UnixNano(), 10))
+	}
+
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+
+	return query
+}
+
+// Page is one page of List results, NextCursor is empty on the last page.
+type Page struct {
+	Binaries   []SignedBinary `json:"binaries"`
+	NextCursor string         `json:"next_cursor"`
+}
+
+type CheckItem struct {
+	Digest    string `json:"digest"`
+	Algorithm string `json:"algorithm"`
+}
+
+// CheckResult is the outcome of checking one digest, Error is set instead of Status when the server could not check it.
+type CheckResult struct {
+	Digest       string        `json:"digest"`
+	Algorithm    string        `json:"algorithm"`
+	Status       Status        `json:"status,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	SignedBinary *SignedBinary `json:"signed_binary,omitempty"`
+}
+
+type checkRequest struct {
+	Items []CheckItem `json:"items"`
+}
+
+type checkResponse struct {
+	Results      []CheckResult `json:"results"`
+	MaxBatchSize int           `json:"max_batch_size"`
+}
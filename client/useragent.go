@@ -0,0 +1,48 @@
+package client
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// ciVariables are checked in order, the first one set names the CI service, CI alone is set by most of them.
+var ciVariables = []struct {
+	variable string
+	name     string
+}{
+	{"GITHUB_ACTIONS", "github-actions"},
+	{"GITLAB_CI", "gitlab-ci"},
+	{"CIRCLECI", "circleci"},
+	{"BUILDKITE", "buildkite"},
+	{"JENKINS_URL", "jenkins"},
+	{"TF_BUILD", "azure-pipelines"},
+	{"TRAVIS", "travis-ci"},
+	{"BITBUCKET_BUILD_NUMBER", "bitbucket-pipelines"},
+	{"TEAMCITY_VERSION", "teamcity"},
+	{"CODEBUILD_BUILD_ID", "aws-codebuild"},
+	{"CI", "ci"},
+}
+
+// CI names the continuous integration service the process runs on, it is empty outside of one.
+func CI() string {
+	for _, v := range ciVariables {
+		if value := os.Getenv(v.variable); value != "" && value != "false" {
+			return v.name
+		}
+	}
+
+	return ""
+}
+
+// UserAgent appends what the API uses to tell callers apart to product, e.g.
+// ccanalytics-cli/0.1.0 (checksign; linux/amd64; ci=github-actions).
+func UserAgent(product string, comments ...string) string {
+	comments = append(comments, runtime.GOOS+"/"+runtime.GOARCH)
+
+	if ci := CI(); ci != "" {
+		comments = append(comments, "ci="+ci)
+	}
+
+	return product + " (" + strings.Join(comments, "; ") + ")"
+}
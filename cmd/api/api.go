@@ -10,12 +10,15 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Gustrb/ccanalytics/internal/analytics"
 	"github.com/Gustrb/ccanalytics/internal/cmdutils"
 	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/Gustrb/ccanalytics/internal/events"
 	"github.com/Gustrb/ccanalytics/internal/handlers"
 	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
 	"github.com/Gustrb/ccanalytics/internal/infrastructure/database/migrator"
 	"github.com/Gustrb/ccanalytics/internal/rest"
+	"github.com/Gustrb/ccanalytics/internal/rest/location"
 	"github.com/labstack/echo/v5"
 	"github.com/labstack/echo/v5/middleware"
 )
@@ -46,6 +49,25 @@ func main() {
 		return
 	}
 
+	if config.Rest.AuthToken == "" {
+		slog.WarnContext(ctx, "REST_AUTH_TOKEN is not set, signing and revoking are open to anyone who can reach the API")
+	}
+
+	locations, err := location.NewChain(config.Location)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to set up location resolvers", "error", err)
+		return
+	}
+
+	if locations.TrustsAnyHeaders() {
+		slog.WarnContext(ctx, "LOCATION_TRUSTED_PROXIES is not set, the CDN location headers of every request are believed, anyone reaching the API without going through the CDN can pick their own location")
+	}
+	defer func() {
+		if err := locations.Close(); err != nil {
+			slog.ErrorContext(ctx, "Failed to close location resolvers", "error", err)
+		}
+	}()
+
 	e := echo.New()
 	recoverConfig := middleware.DefaultRecoverConfig
 
@@ -60,7 +82,8 @@ func main() {
 	e.Use(middleware.CORS("*"))
 	e.Use(rest.WithTransaction)
 	e.Use(rest.WithRequestID)
-	e.Use(rest.WithLocation)
+	e.Use(rest.WithLocation(locations))
+	e.Use(rest.WithUserAgent)
 	e.Use(rest.WithLogging)
 
 	e.GET("/", func(c *echo.Context) error {
@@ -75,12 +98,51 @@ func main() {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Database is not healthy").Wrap(err)
 		}
 
-		return c.JSON(http.StatusOK, map[string]string{})
+		// events are only kept in memory until written, the ones lost on the way are worth keeping an eye on
+		health := map[string]any{
+			"events": events.CurrentStats(),
+		}
+
+		// the settings tell where the data lives, keep them to ourselves in production
+		if config.Environments.EnviromnmentName == config.EnvironmentProduction {
+			return c.JSON(http.StatusOK, health)
+		}
+
+		settings, err := database.CurrentSettings(ctx)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read database settings").Wrap(err)
+		}
+
+		health["database"] = settings
+
+		return c.JSON(http.StatusOK, health)
 	})
 
 	handlers.Register(e)
 
-	slog.InfoContext(ctx, "Starting web server", "addr", config.Rest.Addr)
+	// events not yet rolled up when we stop are picked up on the next start
+	scheduler := analytics.StartScheduler(config.Analytics)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), config.Events.ShutdownTimeout)
+		defer cancel()
+
+		if err := scheduler.Stop(ctx); err != nil {
+			slog.ErrorContext(ctx, "Failed to stop the rollups", "error", err)
+		}
+	}()
+
+	writer := events.Start(config.Events)
+	defer func() {
+		// ctx is canceled once we are asked to stop, pending events still have to be written
+		ctx, cancel := context.WithTimeout(context.Background(), config.Events.ShutdownTimeout)
+		defer cancel()
+
+		if err := writer.Close(ctx); err != nil {
+			slog.ErrorContext(ctx, "Failed to write pending events", "error", err)
+		}
+	}()
+
+	slog.InfoContext(ctx, "Starting web server", "addr", config.Rest.Addr, "database_path", config.Database.Path)
 
 	if err := e.Start(config.Rest.Addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		slog.ErrorContext(ctx, "Failed to start web server", "error", err)
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/bundle"
+	"github.com/Gustrb/ccanalytics/internal/cmdutils"
+	"github.com/urfave/cli/v3"
+)
+
+func main() {
+	cmd := &cli.Command{
+		Flags: []cli.Flag{
+			&cli.Uint16Flag{
+				Name:  "timeout",
+				Usage: "the duration in seconds to wait before timing out the bundle operation",
+				Value: 5, // default timeout of 5 seconds, exporting reads every signed binary
+			},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:  "export",
+				Usage: "export a signed offline verification bundle",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "where to write the bundle",
+						Value: "bundle.json",
+					},
+				},
+				Action: func(ctx context.Context, c *cli.Command) error {
+					ctx, cancel := context.WithTimeout(ctx, time.Duration(c.Uint16("timeout"))*time.Second)
+					defer cancel()
+
+					cleanup, err := cmdutils.SetupMigratedBinary(ctx)
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to set up bundle command", "error", err)
+						return err
+					}
+					defer func() {
+						if err := cleanup(); err != nil {
+							slog.ErrorContext(ctx, "Failed to clean up resources", "error", err)
+						}
+					}()
+
+					envelope, err := bundle.Export(ctx)
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to export bundle", "error", err)
+						return err
+					}
+
+					data, err := json.MarshalIndent(envelope, "", "  ")
+					if err != nil {
+						return err
+					}
+
+					if err := os.WriteFile(c.String("out"), data, 0o644); err != nil {
+						slog.ErrorContext(ctx, "Failed to write bundle", "error", err)
+						return err
+					}
+
+					slog.InfoContext(ctx, "Bundle exported successfully", "out", c.String("out"), "key_id", envelope.KeyID)
+
+					return nil
+				},
+			},
+			{
+				Name:  "verify",
+				Usage: "verify a bundle was not tampered with, without touching the database",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "in",
+						Required: true,
+						Usage:    "the bundle to verify",
+					},
+					&cli.StringSliceFlag{
+						Name:     "public_key",
+						Required: true,
+						Usage:    "the base64 encoded public keys the bundle may be signed with, as printed by keys generate or keys rotate. Pin the next key too before activating it",
+					},
+				},
+				Action: func(ctx context.Context, c *cli.Command) error {
+					b, err := bundle.Load(c.String("in"), c.StringSlice("public_key")...)
+					if err != nil {
+						slog.ErrorContext(ctx, "Bundle is not valid", "error", err)
+						return err
+					}
+
+					generatedAt := time.Unix(0, b.GeneratedAt).Format(time.RFC3339)
+					slog.InfoContext(ctx, "Bundle is valid", "version", b.Version, "generated_at", generatedAt, "binaries", len(b.Binaries), "keys", len(b.Keys), "revocations", len(b.Revocations))
+
+					return nil
+				},
+			},
+		},
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := cmd.Run(ctx, os.Args); err != nil {
+		slog.ErrorContext(ctx, "Failed to run bundle command", "error", err)
+	}
+}
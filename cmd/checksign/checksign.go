@@ -2,37 +2,48 @@ package main
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/Gustrb/ccanalytics/client"
 	"github.com/Gustrb/ccanalytics/internal/binsign"
+	"github.com/Gustrb/ccanalytics/internal/bundle"
 	"github.com/Gustrb/ccanalytics/internal/cmdutils"
-	"github.com/Gustrb/ccanalytics/internal/infrastructure/database/migrator"
+	"github.com/Gustrb/ccanalytics/internal/keys"
 	"github.com/urfave/cli/v3"
 )
 
 func main() {
 	cmd := &cli.Command{
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.StringFlag{
 				Name:     "file_path",
 				Required: true,
 				Usage:    "a valid file path is required in order to be checked",
 			},
+			&cli.StringFlag{
+				Name:  "bundle",
+				Usage: "check against an offline verification bundle instead of the database",
+			},
+			&cli.StringSliceFlag{
+				Name:  "public_key",
+				Usage: "the base64 encoded public keys the bundle may be signed with, required along with --bundle. Pin the next key too before activating it",
+			},
 			&cli.Uint16Flag{
 				Name:  "timeout",
-				Usage: "the duration in seconds to wait before timing out the checksigning process",
+				Usage: "the duration in seconds to wait before timing out the checksigning process, 10 minutes by default with --server",
 				Value: 1, // default timeout of 1 second
 			},
-		},
+		}, cmdutils.RemoteFlags()...),
 		Action: func(ctx context.Context, c *cli.Command) error {
-			timeout := c.Uint16("timeout")
 			slog.InfoContext(ctx, "Starting checksign command")
 
-			ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+			ctx, cancel := context.WithTimeout(ctx, cmdutils.Timeout(c))
 			defer cancel()
 
 			select {
@@ -44,18 +55,15 @@ func main() {
 				}
 
 			default:
-				signedBinary, err := binsign.CheckIfFileIsSigned(ctx, c.String("file_path"))
-				if err != nil {
-					slog.ErrorContext(ctx, "Failed to check if file is signed", "error", err)
-					return err
+				if c.String("bundle") != "" {
+					return checkWithBundle(ctx, c)
 				}
 
-				if signedBinary == nil {
-					slog.InfoContext(ctx, "File is not signed", "file_path", c.String("file_path"))
-				} else {
-					signedAt := time.Unix(0, signedBinary.CreatedAt).Format(time.RFC3339)
-					slog.InfoContext(ctx, "File is signed", "file_path", c.String("file_path"), "signed_at", signedAt)
+				if cmdutils.IsRemote(c) {
+					return checkWithServer(ctx, c)
 				}
+
+				return checkWithDatabase(ctx, c)
 			}
 
 			return nil
@@ -65,10 +73,23 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	cleanup, err := cmdutils.SetupBinary(ctx)
+	if err := cmd.Run(ctx, os.Args); err != nil {
+		slog.ErrorContext(ctx, "Failed to run checksign command", "error", err)
+	}
+}
+
+func checkWithDatabase(ctx context.Context, c *cli.Command) error {
+	// hash before touching the database, large files take a while to read
+	sum, err := digestFile(c.String("file_path"))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to hash file", "error", err)
+		return err
+	}
+
+	cleanup, err := cmdutils.SetupMigratedBinary(ctx)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to set up checksign command", "error", err)
-		return
+		return err
 	}
 	defer func() {
 		if err := cleanup(); err != nil {
@@ -76,18 +97,154 @@ func main() {
 		}
 	}()
 
-	areWeAtTheLatestMigration, err := migrator.AreWeAtTheLatestMigration(ctx)
+	verification, err := binsign.CheckDigest(ctx, sum)
+	if errors.Is(err, binsign.ErrInvalidSignature) || errors.Is(err, binsign.ErrUnknownKey) {
+		slog.WarnContext(ctx, "File is registered but its signature could not be verified", "file_path", c.String("file_path"), "error", err)
+		return err
+	}
 	if err != nil {
-		slog.ErrorContext(ctx, "Failed to check for latest migration", "error", err)
-		return
+		slog.ErrorContext(ctx, "Failed to check if file is signed", "error", err)
+		return err
 	}
 
-	if !areWeAtTheLatestMigration {
-		slog.WarnContext(ctx, "Database is not at the latest migration. Please run the migrator command to apply all pending migrations before running the checksign command.")
-		return
+	if verification.Status == binsign.StatusUnsigned {
+		slog.InfoContext(ctx, "File is not signed", "file_path", c.String("file_path"))
+		return nil
 	}
 
-	if err := cmd.Run(ctx, os.Args); err != nil {
-		slog.ErrorContext(ctx, "Failed to run checksign command", "error", err)
+	signedBinary := verification.SignedBinary
+	signedAt := time.Unix(0, signedBinary.CreatedAt).Format(time.RFC3339)
+
+	if verification.Status == binsign.StatusLegacy {
+		slog.WarnContext(ctx, "File was registered before signatures were recorded, there is no signature to verify", "file_path", c.String("file_path"), "registered_at", signedAt)
+		return nil
+	}
+
+	// legacy binaries can be revoked too, they just have no key to report
+	key := &keys.Key{}
+	if !signedBinary.IsLegacy() {
+		key, err = binsign.SigningKey(ctx, signedBinary)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to get signing key", "error", err)
+			return err
+		}
+	}
+
+	if verification.Status == binsign.StatusRevoked {
+		revokedAt := time.Unix(0, signedBinary.RevokedAt).Format(time.RFC3339)
+		slog.WarnContext(ctx, "File signature has been revoked", "file_path", c.String("file_path"), "signed_at", signedAt, "revoked_at", revokedAt, "reason", signedBinary.RevocationReason, "key_id", key.Fingerprint)
+
+		return nil
+	}
+
+	if verification.Status == binsign.StatusUntrusted {
+		slog.WarnContext(ctx, "File was signed by a compromised key and must not be trusted", "file_path", c.String("file_path"), "signed_at", signedAt, "key_id", key.Fingerprint)
+
+		return nil
+	}
+
+	slog.InfoContext(ctx, "File is signed", "file_path", c.String("file_path"), "signed_at", signedAt, "key_id", key.Fingerprint, "key_status", key.Status)
+
+	return nil
+}
+
+func checkWithBundle(ctx context.Context, c *cli.Command) error {
+	// the keys embedded in the bundle prove nothing, whoever tampered with it could have embedded their own
+	if len(c.StringSlice("public_key")) == 0 {
+		slog.ErrorContext(ctx, "A public key is required to check against a bundle")
+		return bundle.ErrPublicKeyRequired
 	}
+
+	b, err := bundle.Load(c.String("bundle"), c.StringSlice("public_key")...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load bundle", "error", err)
+		return err
+	}
+
+	result, err := b.CheckFileAt(c.String("file_path"))
+	if errors.Is(err, binsign.ErrInvalidSignature) || errors.Is(err, binsign.ErrUnknownKey) {
+		slog.WarnContext(ctx, "File is in the bundle but its signature could not be verified", "file_path", c.String("file_path"), "error", err)
+		return err
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to check if file is signed", "error", err)
+		return err
+	}
+
+	bundledAt := time.Unix(0, b.GeneratedAt).Format(time.RFC3339)
+
+	switch result.Status {
+	case binsign.StatusUnsigned:
+		slog.InfoContext(ctx, "File is not signed", "file_path", c.String("file_path"), "bundle_generated_at", bundledAt)
+	case binsign.StatusRevoked:
+		revokedAt := time.Unix(0, result.RevokedAt).Format(time.RFC3339)
+		slog.WarnContext(ctx, "File signature has been revoked", "file_path", c.String("file_path"), "revoked_at", revokedAt, "reason", result.RevocationReason, "bundle_generated_at", bundledAt)
+	case binsign.StatusLegacy:
+		registeredAt := time.Unix(0, result.SignedAt).Format(time.RFC3339)
+		slog.WarnContext(ctx, "File was registered before signatures were recorded, there is no signature to verify", "file_path", c.String("file_path"), "registered_at", registeredAt, "bundle_generated_at", bundledAt)
+	case binsign.StatusUntrusted:
+		signedAt := time.Unix(0, result.SignedAt).Format(time.RFC3339)
+		slog.WarnContext(ctx, "File was signed by a compromised key and must not be trusted", "file_path", c.String("file_path"), "signed_at", signedAt, "key_id", result.KeyID, "bundle_generated_at", bundledAt)
+	case binsign.StatusSigned:
+		signedAt := time.Unix(0, result.SignedAt).Format(time.RFC3339)
+		slog.InfoContext(ctx, "File is signed", "file_path", c.String("file_path"), "signed_at", signedAt, "key_id", result.KeyID, "key_status", result.KeyStatus, "bundle_generated_at", bundledAt)
+	}
+
+	return nil
+}
+
+func checkWithServer(ctx context.Context, c *cli.Command) error {
+	api, err := cmdutils.NewClient(c, "checksign")
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to set up checksign command", "error", err)
+		return err
+	}
+
+	// only the digest is sent to the server, the file never leaves the machine
+	sum, err := digestFile(c.String("file_path"))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to hash file", "error", err)
+		return err
+	}
+
+	result, err := api.CheckDigest(ctx, hex.EncodeToString(sum))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to check if file is signed", "error", err)
+		return err
+	}
+
+	if result.Error != "" {
+		slog.WarnContext(ctx, "File is registered but its signature could not be verified", "file_path", c.String("file_path"), "error", result.Error)
+		return errors.New(result.Error)
+	}
+
+	switch result.Status {
+	case client.StatusUnsigned:
+		slog.InfoContext(ctx, "File is not signed", "file_path", c.String("file_path"))
+	case client.StatusRevoked:
+		signedAt := time.Unix(0, result.SignedBinary.SignedAt).Format(time.RFC3339)
+		revokedAt := time.Unix(0, result.SignedBinary.RevokedAt).Format(time.RFC3339)
+		slog.WarnContext(ctx, "File signature has been revoked", "file_path", c.String("file_path"), "signed_at", signedAt, "revoked_at", revokedAt, "reason", result.SignedBinary.RevocationReason, "key_id", result.SignedBinary.KeyID)
+	case client.StatusLegacy:
+		registeredAt := time.Unix(0, result.SignedBinary.SignedAt).Format(time.RFC3339)
+		slog.WarnContext(ctx, "File was registered before signatures were recorded, there is no signature to verify", "file_path", c.String("file_path"), "registered_at", registeredAt)
+	case client.StatusUntrusted:
+		signedAt := time.Unix(0, result.SignedBinary.SignedAt).Format(time.RFC3339)
+		slog.WarnContext(ctx, "File was signed by a compromised key and must not be trusted", "file_path", c.String("file_path"), "signed_at", signedAt, "key_id", result.SignedBinary.KeyID)
+	case client.StatusSigned:
+		signedAt := time.Unix(0, result.SignedBinary.SignedAt).Format(time.RFC3339)
+		slog.InfoContext(ctx, "File is signed", "file_path", c.String("file_path"), "signed_at", signedAt, "key_id", result.SignedBinary.KeyID, "key_status", result.SignedBinary.KeyStatus)
+	}
+
+	return nil
+}
+
+func digestFile(filePath string) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return binsign.Digest(f)
 }
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/binsign"
+	"github.com/Gustrb/ccanalytics/internal/cmdutils"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database/migrator"
+	"github.com/Gustrb/ccanalytics/internal/keys"
+	"github.com/urfave/cli/v3"
+)
+
+func main() {
+	cmd := &cli.Command{
+		Flags: []cli.Flag{
+			&cli.Uint16Flag{
+				Name:  "timeout",
+				Usage: "the duration in seconds to wait before timing out the key operation",
+				Value: 1, // default timeout of 1 second
+			},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:  "generate",
+				Usage: "generate a new signing key",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "activate",
+						Usage: "immediately make the generated key the active signing key",
+					},
+				},
+				Action: func(ctx context.Context, c *cli.Command) error {
+					ctx, cancel := withTimeout(ctx, c)
+					defer cancel()
+
+					k, err := keys.Generate(ctx)
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to generate key", "error", err)
+						return err
+					}
+
+					if c.Bool("activate") {
+						if k, err = keys.Activate(ctx, k.Fingerprint); err != nil {
+							slog.ErrorContext(ctx, "Failed to activate key", "error", err)
+							return err
+						}
+					}
+
+					slog.InfoContext(ctx, "Key generated successfully", "key_id", k.Fingerprint, "status", k.Status, "public_key", k.PublicKey)
+
+					return nil
+				},
+			},
+			{
+				Name:  "import",
+				Usage: "import an existing base64 encoded Ed25519 private key",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "private_key",
+						Required: true,
+						Usage:    "the base64 encoded Ed25519 seed or private key",
+					},
+					&cli.BoolFlag{
+						Name:  "activate",
+						Usage: "immediately make the imported key the active signing key",
+					},
+				},
+				Action: func(ctx context.Context, c *cli.Command) error {
+					ctx, cancel := withTimeout(ctx, c)
+					defer cancel()
+
+					k, err := keys.Import(ctx, c.String("private_key"))
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to import key", "error", err)
+						return err
+					}
+
+					adopted, err := binsign.AdoptUnattributedSignatures(ctx, k)
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to attribute existing signatures to key", "error", err)
+						return err
+					}
+
+					if c.Bool("activate") {
+						if k, err = keys.Activate(ctx, k.Fingerprint); err != nil {
+							slog.ErrorContext(ctx, "Failed to activate key", "error", err)
+							return err
+						}
+					}
+
+					slog.InfoContext(ctx, "Key imported successfully", "key_id", k.Fingerprint, "status", k.Status, "adopted_signatures", adopted)
+
+					return nil
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "list every known key and its status",
+				Action: func(ctx context.Context, c *cli.Command) error {
+					ctx, cancel := withTimeout(ctx, c)
+					defer cancel()
+
+					list, err := keys.List(ctx)
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to list keys", "error", err)
+						return err
+					}
+
+					if len(list) == 0 {
+						slog.InfoContext(ctx, "No keys found")
+					}
+
+					for _, k := range list {
+						slog.InfoContext(ctx, "Key", "key_id", k.Fingerprint, "algorithm", k.Algorithm, "status", k.Status, "created_at", formatTimestamp(k.CreatedAt), "activated_at", formatTimestamp(k.ActivatedAt), "retired_at", formatTimestamp(k.RetiredAt))
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "rotate",
+				Usage: "generate a new key and make it the active signing key",
+				Action: func(ctx context.Context, c *cli.Command) error {
+					ctx, cancel := withTimeout(ctx, c)
+					defer cancel()
+
+					k, err := keys.Rotate(ctx)
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to rotate key", "error", err)
+						return err
+					}
+
+					slog.InfoContext(ctx, "Key rotated successfully", "key_id", k.Fingerprint, "public_key", k.PublicKey)
+
+					return nil
+				},
+			},
+			{
+				Name:  "retire",
+				Usage: "take a key out of service",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "key_id",
+						Required: true,
+						Usage:    "the fingerprint of the key to retire",
+					},
+					&cli.BoolFlag{
+						Name:  "compromised",
+						Usage: "flag the key as compromised so its signatures are no longer trusted",
+					},
+				},
+				Action: func(ctx context.Context, c *cli.Command) error {
+					ctx, cancel := withTimeout(ctx, c)
+					defer cancel()
+
+					k, err := keys.Retire(ctx, c.String("key_id"), c.Bool("compromised"))
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to retire key", "error", err)
+						return err
+					}
+
+					slog.InfoContext(ctx, "Key retired successfully", "key_id", k.Fingerprint, "status", k.Status)
+
+					return nil
+				},
+			},
+		},
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	cleanup, err := cmdutils.SetupBinary(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to set up keys command", "error", err)
+		return
+	}
+	defer func() {
+		if err := cleanup(); err != nil {
+			slog.ErrorContext(ctx, "Failed to clean up resources", "error", err)
+		}
+	}()
+
+	areWeAtTheLatestMigration, err := migrator.AreWeAtTheLatestMigration(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to check for latest migration", "error", err)
+		return
+	}
+
+	if !areWeAtTheLatestMigration {
+		slog.WarnContext(ctx, "Database is not at the latest migration. Please run the migrator command to apply all pending migrations before running the keys command.")
+		return
+	}
+
+	if err := cmd.Run(ctx, os.Args); err != nil {
+		slog.ErrorContext(ctx, "Failed to run keys command", "error", err)
+	}
+}
+
+func withTimeout(ctx context.Context, c *cli.Command) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(c.Uint16("timeout"))*time.Second)
+}
+
+func formatTimestamp(ts int64) string {
+	if ts == 0 {
+		return ""
+	}
+
+	return time.Unix(0, ts).Format(time.RFC3339)
+}
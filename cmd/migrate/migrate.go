@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -19,54 +23,264 @@ func main() {
 		Flags: []cli.Flag{
 			&cli.Uint16Flag{
 				Name:  "timeout",
-				Usage: "the duration in seconds to wait before timing out the signing process",
+				Usage: "the duration in seconds to wait before timing out the migration",
 				Value: 1, // default timeout of 1 second
 			},
 		},
-		Action: func(ctx context.Context, c *cli.Command) error {
-			timeout := c.Uint16("timeout")
-			slog.InfoContext(ctx, "Starting migrate command")
-
-			ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
-			defer cancel()
-
-			select {
-			case <-ctx.Done():
-				if ctx.Err() == context.DeadlineExceeded {
-					slog.WarnContext(ctx, "Signing process timed out")
-				} else {
-					slog.InfoContext(ctx, "Signing process completed successfully")
-				}
-
-			default:
-				switch c.Args().Get(0) {
-				case "up":
-					if err := migrator.MigrateUp(ctx); err != nil {
+		Commands: []*cli.Command{
+			{
+				Name:  "up",
+				Usage: "apply every pending migration",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "allow-out-of-order",
+						Usage: "apply pending migrations even when they are older than the last applied one",
+					},
+				},
+				Action: withDatabase(func(ctx context.Context, c *cli.Command) error {
+					ctx, cancel := withTimeout(ctx, c)
+					defer cancel()
+
+					if err := migrator.MigrateUp(ctx, migrator.WithAllowOutOfOrder(c.Bool("allow-out-of-order"))); err != nil {
 						slog.ErrorContext(ctx, "Failed to migrate up", "error", err)
 						return err
 					}
-				}
-			}
 
-			return nil
+					return nil
+				}),
+			},
+			{
+				Name:      "down",
+				Usage:     "roll back the last n applied migrations",
+				ArgsUsage: "[n]",
+				Action: withDatabase(func(ctx context.Context, c *cli.Command) error {
+					ctx, cancel := withTimeout(ctx, c)
+					defer cancel()
+
+					n := 1
+					if c.Args().Present() {
+						var err error
+						if n, err = strconv.Atoi(c.Args().First()); err != nil || n < 1 {
+							return fmt.Errorf("%q: expected a positive number of migrations to roll back", c.Args().First())
+						}
+					}
+
+					if err := migrator.MigrateDown(ctx, n); err != nil {
+						slog.ErrorContext(ctx, "Failed to migrate down", "error", err)
+						return err
+					}
+
+					return nil
+				}),
+			},
+			{
+				Name:      "to",
+				Usage:     "apply or roll back migrations until the given one is the last applied",
+				ArgsUsage: "<timestamp>",
+				Action: withDatabase(func(ctx context.Context, c *cli.Command) error {
+					ctx, cancel := withTimeout(ctx, c)
+					defer cancel()
+
+					timestamp, err := strconv.Atoi(c.Args().First())
+					if err != nil {
+						return fmt.Errorf("%q: expected the timestamp of a migration", c.Args().First())
+					}
+
+					if err := migrator.MigrateTo(ctx, timestamp); err != nil {
+						slog.ErrorContext(ctx, "Failed to migrate", "timestamp", timestamp, "error", err)
+						return err
+					}
+
+					return nil
+				}),
+			},
+			{
+				Name:  "redo",
+				Usage: "roll back the last applied migration and apply it again",
+				Action: withDatabase(func(ctx context.Context, c *cli.Command) error {
+					ctx, cancel := withTimeout(ctx, c)
+					defer cancel()
+
+					if err := migrator.Redo(ctx); err != nil {
+						slog.ErrorContext(ctx, "Failed to redo migration", "error", err)
+						return err
+					}
+
+					return nil
+				}),
+			},
+			{
+				Name:  "status",
+				Usage: "list applied and pending migrations",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "either text or json",
+						Value: "text",
+					},
+				},
+				Action: withDatabase(func(ctx context.Context, c *cli.Command) error {
+					ctx, cancel := withTimeout(ctx, c)
+					defer cancel()
+
+					statuses, err := migrator.Status(ctx)
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to get migration status", "error", err)
+						return err
+					}
+
+					switch c.String("format") {
+					case "json":
+						encoder := json.NewEncoder(os.Stdout)
+						encoder.SetIndent("", "  ")
+
+						return encoder.Encode(statuses)
+					case "text":
+						for _, s := range statuses {
+							if s.Applied {
+								slog.InfoContext(ctx, "Applied", "filename", s.Filename, "timestamp", s.Timestamp, "applied_at", time.Unix(0, s.AppliedAt).Format(time.RFC3339))
+							} else {
+								slog.InfoContext(ctx, "Pending", "filename", s.Filename, "timestamp", s.Timestamp)
+							}
+						}
+
+						return nil
+					default:
+						return fmt.Errorf("%q: expected text or json", c.String("format"))
+					}
+				}),
+			},
+			{
+				Name:  "verify",
+				Usage: "report applied migrations that were edited or removed since, without applying anything",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "either text or json",
+						Value: "text",
+					},
+				},
+				Action: withDatabase(func(ctx context.Context, c *cli.Command) error {
+					ctx, cancel := withTimeout(ctx, c)
+					defer cancel()
+
+					drifts, err := migrator.Verify(ctx)
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to verify migrations", "error", err)
+						return err
+					}
+
+					switch c.String("format") {
+					case "json":
+						encoder := json.NewEncoder(os.Stdout)
+						encoder.SetIndent("", "  ")
+
+						if err := encoder.Encode(drifts); err != nil {
+							return err
+						}
+					case "text":
+						for _, d := range drifts {
+							slog.WarnContext(ctx, "Migration drift", "kind", d.Kind, "filename", d.Filename, "recorded_hash", d.RecordedHash, "current_hash", d.CurrentHash)
+						}
+					default:
+						return fmt.Errorf("%q: expected text or json", c.String("format"))
+					}
+
+					for _, d := range drifts {
+						if d.Kind != migrator.DriftUnrecorded {
+							return migrator.ErrMigrationDrift
+						}
+					}
+
+					slog.InfoContext(ctx, "Applied migrations match the codebase", "unrecorded", len(drifts))
+
+					return nil
+				}),
+			},
+			{
+				Name:      "create",
+				Usage:     "write an empty migration for every dialect",
+				ArgsUsage: "<name>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "dir",
+						Usage: "the migrations directory, holding one directory per dialect",
+						Value: migrator.SourceDir,
+					},
+				},
+				Action: func(ctx context.Context, c *cli.Command) error {
+					created, err := migrator.Create(c.String("dir"), strings.Join(c.Args().Slice(), " "))
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to create migration", "error", err)
+						return err
+					}
+
+					for _, filename := range created {
+						slog.InfoContext(ctx, "Created migration", "filename", filename)
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "lint",
+				Usage: "check that every migration parses, can be rolled back and has a unique timestamp",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "dir",
+						Usage: "the migrations directory, holding one directory per dialect",
+						Value: migrator.SourceDir,
+					},
+				},
+				Action: func(ctx context.Context, c *cli.Command) error {
+					issues, err := migrator.Lint(os.DirFS(c.String("dir")))
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to lint migrations", "error", err)
+						return err
+					}
+
+					for _, issue := range issues {
+						slog.WarnContext(ctx, "Migration issue", "dir", c.String("dir"), "filename", issue.Filename, "problem", issue.Problem)
+					}
+
+					if len(issues) > 0 {
+						return fmt.Errorf("found %d migration issues", len(issues))
+					}
+
+					slog.InfoContext(ctx, "Migrations are valid", "dir", c.String("dir"))
+
+					return nil
+				},
+			},
 		},
 	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	cleanup, err := cmdutils.SetupBinary(ctx)
-	if err != nil {
-		slog.ErrorContext(ctx, "Failed to set up migrate command", "error", err)
-		return
+	if err := cmd.Run(ctx, os.Args); err != nil {
+		slog.ErrorContext(ctx, "Failed to run migrate command", "error", err)
 	}
-	defer func() {
-		if err := cleanup(); err != nil {
-			slog.ErrorContext(ctx, "Failed to clean up resources", "error", err)
+}
+
+func withTimeout(ctx context.Context, c *cli.Command) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(c.Uint16("timeout"))*time.Second)
+}
+
+// withDatabase connects to the database around action, create and lint only work on files and run without one.
+func withDatabase(action cli.ActionFunc) cli.ActionFunc {
+	return func(ctx context.Context, c *cli.Command) error {
+		cleanup, err := cmdutils.SetupBinary(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to set up migrate command", "error", err)
+			return err
 		}
-	}()
+		defer func() {
+			if err := cleanup(); err != nil {
+				slog.ErrorContext(ctx, "Failed to clean up resources", "error", err)
+			}
+		}()
 
-	if err := cmd.Run(ctx, os.Args); err != nil {
-		slog.ErrorContext(ctx, "Failed to run migrate command", "error", err)
+		return action(ctx, c)
 	}
 }
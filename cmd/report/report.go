@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/cmdutils"
+	"github.com/Gustrb/ccanalytics/internal/events"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database/migrator"
+	"github.com/urfave/cli/v3"
+)
+
+func main() {
+	cmd := &cli.Command{
+		Flags: []cli.Flag{
+			&cli.Uint16Flag{
+				Name:  "timeout",
+				Usage: "the duration in seconds to wait before timing out the report",
+				Value: 5,
+			},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:  "unknown-hashes",
+				Usage: "list the unsigned hashes checked most often, and where from",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "how many hashes to list",
+						Value: events.DefaultUnknownLimit,
+					},
+					&cli.DurationFlag{
+						Name:  "within",
+						Usage: "only list hashes checked within this long, e.g. 168h, everything when zero",
+					},
+					&cli.StringFlag{
+						Name:  "country_code",
+						Usage: "only list hashes checked at least once from this country",
+					},
+					&cli.StringFlag{
+						Name:  "sort",
+						Usage: "either total, last_seen or first_seen",
+						Value: string(events.UnknownSortTotal),
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "either text or json",
+						Value: "text",
+					},
+				},
+				Action: func(ctx context.Context, c *cli.Command) error {
+					ctx, cancel := withTimeout(ctx, c)
+					defer cancel()
+
+					var seenAfter int64
+					if within := c.Duration("within"); within > 0 {
+						seenAfter = time.Now().Add(-within).UnixNano()
+					}
+
+					reports, err := events.ListUnknown(ctx, events.UnknownFilter{
+						SeenAfter:   seenAfter,
+						CountryCode: strings.ToUpper(c.String("country_code")),
+						Sort:        events.UnknownSort(c.String("sort")),
+						Limit:       c.Int("limit"),
+					})
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to list unknown hashes", "error", err)
+						return err
+					}
+
+					switch c.String("format") {
+					case "json":
+						encoder := json.NewEncoder(os.Stdout)
+						encoder.SetIndent("", "  ")
+
+						return encoder.Encode(reports)
+					case "text":
+						if len(reports) == 0 {
+							slog.InfoContext(ctx, "No unknown hashes found")
+						}
+
+						for _, r := range reports {
+							slog.InfoContext(ctx, "Unknown hash", "hash", r.Hash, "total", r.Total, "first_seen_at", formatTimestamp(r.FirstSeenAt), "last_seen_at", formatTimestamp(r.LastSeenAt), "countries", formatCountries(r.Countries))
+						}
+
+						return nil
+					default:
+						return fmt.Errorf("unknown format %q, must be either text or json", c.String("format"))
+					}
+				},
+			},
+		},
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	cleanup, err := cmdutils.SetupBinary(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to set up report command", "error", err)
+		return
+	}
+	defer func() {
+		if err := cleanup(); err != nil {
+			slog.ErrorContext(ctx, "Failed to clean up resources", "error", err)
+		}
+	}()
+
+	areWeAtTheLatestMigration, err := migrator.AreWeAtTheLatestMigration(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to check for latest migration", "error", err)
+		return
+	}
+
+	if !areWeAtTheLatestMigration {
+		slog.WarnContext(ctx, "Database is not at the latest migration. Please run the migrator command to apply all pending migrations before running the report command.")
+		return
+	}
+
+	if err := cmd.Run(ctx, os.Args); err != nil {
+		slog.ErrorContext(ctx, "Failed to run report command", "error", err)
+	}
+}
+
+func withTimeout(ctx context.Context, c *cli.Command) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(c.Uint16("timeout"))*time.Second)
+}
+
+func formatTimestamp(ts int64) string {
+	if ts == 0 {
+		return ""
+	}
+
+	return time.Unix(0, ts).Format(time.RFC3339)
+}
+
+// formatCountries lists the countries as BR=12 US=3, checks from an unknown country show up as ??.
+func formatCountries(countries []*events.UnknownHashCountry) string {
+	parts := make([]string, 0, len(countries))
+	for _, c := range countries {
+		code := c.CountryCode
+		if code == "" {
+			code = "??"
+		}
+
+		parts = append(parts, fmt.Sprintf("%s=%d", code, c.Total))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/binsign"
+	"github.com/Gustrb/ccanalytics/internal/cmdutils"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database/migrator"
+	"github.com/urfave/cli/v3"
+)
+
+func main() {
+	reasons := make([]string, 0, len(binsign.RevocationReasons))
+	for _, r := range binsign.RevocationReasons {
+		reasons = append(reasons, string(r))
+	}
+
+	cmd := &cli.Command{
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "reason",
+				Usage: "why the signature is being revoked, one of: " + strings.Join(reasons, ", "),
+				Value: string(binsign.ReasonUnspecified),
+			},
+			&cli.Uint16Flag{
+				Name:  "timeout",
+				Usage: "the duration in seconds to wait before timing out the revoking process",
+				Value: 1, // default timeout of 1 second
+			},
+		},
+		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
+			{
+				Required: true,
+				Flags: [][]cli.Flag{
+					{
+						&cli.StringFlag{
+							Name:  "file_path",
+							Usage: "the file whose signature should be revoked",
+						},
+					},
+					{
+						&cli.StringFlag{
+							Name:  "hash",
+							Usage: "the hex encoded SHA-256 of the binary whose signature should be revoked",
+						},
+					},
+				},
+			},
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			timeout := c.Uint16("timeout")
+			slog.InfoContext(ctx, "Starting revoke command")
+
+			ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+			defer cancel()
+
+			reason, err := binsign.ParseRevocationReason(c.String("reason"))
+			if err != nil {
+				slog.ErrorContext(ctx, "Invalid revocation reason", "error", err)
+				return err
+			}
+
+			var signedBinary *binsign.SignedBinary
+			if c.String("file_path") != "" {
+				signedBinary, err = binsign.RevokeFileAt(ctx, c.String("file_path"), reason)
+			} else {
+				signedBinary, err = binsign.Revoke(ctx, c.String("hash"), reason)
+			}
+
+			if errors.Is(err, binsign.ErrAlreadyRevoked) {
+				slog.WarnContext(ctx, "Signature has already been revoked, skipping")
+				return nil
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to revoke signature", "error", err)
+				return err
+			}
+
+			slog.InfoContext(ctx, "Signature revoked successfully", "hash", signedBinary.Hash, "reason", signedBinary.RevocationReason)
+
+			return nil
+		},
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	cleanup, err := cmdutils.SetupBinary(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to set up revoke command", "error", err)
+		return
+	}
+	defer func() {
+		if err := cleanup(); err != nil {
+			slog.ErrorContext(ctx, "Failed to clean up resources", "error", err)
+		}
+	}()
+
+	areWeAtTheLatestMigration, err := migrator.AreWeAtTheLatestMigration(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to check for latest migration", "error", err)
+		return
+	}
+
+	if !areWeAtTheLatestMigration {
+		slog.WarnContext(ctx, "Database is not at the latest migration. Please run the migrator command to apply all pending migrations before running the revoke command.")
+		return
+	}
+
+	if err := cmd.Run(ctx, os.Args); err != nil {
+		slog.ErrorContext(ctx, "Failed to run revoke command", "error", err)
+	}
+}
@@ -4,36 +4,64 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
-	"time"
 
+	"github.com/Gustrb/ccanalytics/client"
 	"github.com/Gustrb/ccanalytics/internal/binsign"
 	"github.com/Gustrb/ccanalytics/internal/cmdutils"
-	"github.com/Gustrb/ccanalytics/internal/infrastructure/database/migrator"
 	"github.com/urfave/cli/v3"
 )
 
 func main() {
 	cmd := &cli.Command{
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.StringFlag{
 				Name:     "file_path",
 				Required: true,
 				Usage:    "a valid file path is required in order to be signed",
 			},
+			&cli.StringFlag{
+				Name:  "artifact_name",
+				Usage: "the name of the artifact the file is a build of",
+			},
+			&cli.StringFlag{
+				Name:  "version",
+				Usage: "the release version of the artifact",
+			},
+			&cli.StringFlag{
+				Name:  "os",
+				Usage: "the operating system the file targets",
+			},
+			&cli.StringFlag{
+				Name:  "arch",
+				Usage: "the architecture the file targets",
+			},
+			&cli.StringFlag{
+				Name:  "filename",
+				Usage: "the original filename, defaults to the base name of file_path",
+			},
+			&cli.StringFlag{
+				Name:  "uploader",
+				Usage: "who is signing the file",
+			},
+			&cli.StringSliceFlag{
+				Name:  "label",
+				Usage: "a free-form key=value label, may be repeated",
+			},
 			&cli.Uint16Flag{
 				Name:  "timeout",
-				Usage: "the duration in seconds to wait before timing out the signing process",
+				Usage: "the duration in seconds to wait before timing out the signing process, 10 minutes by default with --server",
 				Value: 1, // default timeout of 1 second
 			},
-		},
+		}, cmdutils.RemoteFlags()...),
 		Action: func(ctx context.Context, c *cli.Command) error {
-			timeout := c.Uint16("timeout")
 			slog.InfoContext(ctx, "Starting signer command")
 
-			ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+			ctx, cancel := context.WithTimeout(ctx, cmdutils.Timeout(c))
 			defer cancel()
 
 			select {
@@ -45,12 +73,34 @@ func main() {
 				}
 
 			default:
-				if err := binsign.SignFileAt(ctx, c.String("file_path")); err != nil {
-					if errors.Is(err, binsign.ErrDuplicateHash) {
-						slog.WarnContext(ctx, "File has already been signed, skipping", "file_path", c.String("file_path"))
-						return nil
-					}
+				labels, err := binsign.ParseLabels(c.StringSlice("label"))
+				if err != nil {
+					slog.ErrorContext(ctx, "Invalid label", "error", err)
+					return err
+				}
+
+				metadata := binsign.Metadata{
+					ArtifactName: c.String("artifact_name"),
+					Version:      c.String("version"),
+					OS:           c.String("os"),
+					Arch:         c.String("arch"),
+					Filename:     c.String("filename"),
+					Uploader:     c.String("uploader"),
+					Labels:       labels,
+				}
+
+				if cmdutils.IsRemote(c) {
+					err = signRemote(ctx, c, metadata)
+				} else {
+					err = signLocal(ctx, c, metadata)
+				}
 
+				if errors.Is(err, binsign.ErrDuplicateHash) {
+					slog.WarnContext(ctx, "File has already been signed, skipping", "file_path", c.String("file_path"))
+					return nil
+				}
+
+				if err != nil {
 					slog.ErrorContext(ctx, "Failed to sign file", "error", err)
 					return err
 				}
@@ -65,10 +115,16 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	cleanup, err := cmdutils.SetupBinary(ctx)
+	if err := cmd.Run(ctx, os.Args); err != nil {
+		slog.ErrorContext(ctx, "Failed to run signer command", "error", err)
+	}
+}
+
+func signLocal(ctx context.Context, c *cli.Command, metadata binsign.Metadata) error {
+	cleanup, err := cmdutils.SetupMigratedBinary(ctx)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to set up signer command", "error", err)
-		return
+		return err
 	}
 	defer func() {
 		if err := cleanup(); err != nil {
@@ -76,18 +132,41 @@ func main() {
 		}
 	}()
 
-	areWeAtTheLatestMigration, err := migrator.AreWeAtTheLatestMigration(ctx)
+	_, err = binsign.SignFileAt(ctx, c.String("file_path"), metadata)
+
+	return err
+}
+
+func signRemote(ctx context.Context, c *cli.Command, metadata binsign.Metadata) error {
+	api, err := cmdutils.NewClient(c, "signer")
 	if err != nil {
-		slog.ErrorContext(ctx, "Failed to check for latest migration", "error", err)
-		return
+		return err
 	}
 
-	if !areWeAtTheLatestMigration {
-		slog.WarnContext(ctx, "Database is not at the latest migration. Please run the migrator command to apply all pending migrations before running the signer command.")
-		return
+	f, err := os.Open(c.String("file_path"))
+	if err != nil {
+		return err
 	}
+	defer f.Close()
 
-	if err := cmd.Run(ctx, os.Args); err != nil {
-		slog.ErrorContext(ctx, "Failed to run signer command", "error", err)
+	filename := metadata.Filename
+	if filename == "" {
+		filename = filepath.Base(c.String("file_path"))
 	}
+
+	_, err = api.Sign(ctx, filename, f, client.Metadata{
+		ArtifactName: metadata.ArtifactName,
+		Version:      metadata.Version,
+		OS:           metadata.OS,
+		Arch:         metadata.Arch,
+		Uploader:     metadata.Uploader,
+		Labels:       metadata.Labels,
+	})
+
+	// keep the local and remote modes reporting duplicates the same way
+	if apiErr, ok := errors.AsType[*client.APIError](err); ok && apiErr.StatusCode == http.StatusConflict {
+		return binsign.ErrDuplicateHash
+	}
+
+	return err
 }
@@ -4,10 +4,15 @@ go 1.26.0
 
 require (
 	github.com/labstack/echo/v5 v5.0.4
+	github.com/lib/pq v1.12.3
+	github.com/oschwald/maxminddb-golang/v2 v2.4.1
 	github.com/stretchr/testify v1.11.1
 )
 
-require golang.org/x/time v0.14.0 // indirect
+require (
+	golang.org/x/sys v0.46.0 // indirect
+	golang.org/x/time v0.14.0 // indirect
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
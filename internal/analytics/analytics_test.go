@@ -0,0 +1,200 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/Gustrb/ccanalytics/internal/events"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database/migrator"
+	"github.com/Gustrb/ccanalytics/internal/rest/location"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	ctx := context.Background()
+
+	dir, err := os.MkdirTemp("", "ccanalytics-analytics-test")
+	if err != nil {
+		slog.Error("Failed to create temp dir", "error", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	cleanup, err := database.Connect(ctx, config.DatabaseConfig{
+		Path:        filepath.Join(dir, "app.db"),
+		JournalMode: "wal",
+		ForeignKeys: true,
+	})
+	if err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		return 1
+	}
+	defer cleanup()
+
+	if err := migrator.MigrateUp(ctx); err != nil {
+		slog.Error("Failed to migrate database", "error", err)
+		return 1
+	}
+
+	return m.Run()
+}
+
+var day = time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC)
+
+type testEvent struct {
+	at      time.Duration
+	hash    string
+	outcome events.Outcome
+	country string
+	mobile  bool
+	latency int64
+}
+
+// record writes the events as if they had happened at day plus their offset.
+func record(t *testing.T, evs ...testEvent) {
+	t.Helper()
+
+	// a queue that fits them all, none of them is dropped
+	w := events.Start(config.EventsConfig{BufferSize: max(len(evs), 1), BatchSize: 10, FlushInterval: time.Hour})
+
+	for _, ev := range evs {
+		mobile := ev.mobile
+		e := events.NewEvent(
+			events.WithKind(events.KindCheckSign),
+			events.WithLocation(&location.Location{CountryCode: ev.country, IsMobile: &mobile}),
+		)
+		e.Hash = ev.hash
+		e.Outcome = ev.outcome
+		e.LatencyMicros = ev.latency
+		e.CreatedAt = day.Add(ev.at).UnixNano()
+
+		w.Record(e)
+	}
+
+	require.NoError(t, w.Close(context.Background()))
+}
+
+func dayFilter(granularity Granularity) Filter {
+	return Filter{
+		Granularity: granularity,
+		From:        day.UnixNano(),
+		To:          day.Add(48 * time.Hour).UnixNano(),
+	}
+}
+
+func TestShouldRollUpEventsIncrementally(t *testing.T) {
+	ctx := context.Background()
+
+	record(t,
+		testEvent{at: 10 * time.Minute, hash: "aaa", outcome: events.OutcomeSigned, country: "BR", mobile: true, latency: 100},
+		testEvent{at: 20 * time.Minute, hash: "aaa", outcome: events.OutcomeSigned, country: "BR", mobile: true, latency: 300},
+		testEvent{at: 90 * time.Minute, hash: "aaa", outcome: events.OutcomeUnsigned, country: "US", latency: 50},
+		testEvent{at: 25 * time.Hour, hash: "bbb", outcome: events.OutcomeSigned, country: "BR", latency: 70},
+	)
+
+	folded, err := RollUp(ctx, 3)
+	require.NoError(t, err)
+	require.Equal(t, 4, folded)
+
+	points, err := Timeseries(ctx, dayFilter(GranularityHour), []Dimension{DimensionCountryCode, DimensionDevice})
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+
+	require.Equal(t, day.UnixNano(), points[0].BucketStart)
+	require.Equal(t, int64(2), points[0].Total)
+	require.Equal(t, int64(200), points[0].AverageLatencyMicros)
+	require.Equal(t, map[Dimension]string{DimensionCountryCode: "BR", DimensionDevice: string(DeviceMobile)}, points[0].Group)
+
+	require.Equal(t, day.Add(time.Hour).UnixNano(), points[1].BucketStart)
+	require.Equal(t, map[Dimension]string{DimensionCountryCode: "US", DimensionDevice: string(DeviceUnknown)}, points[1].Group)
+
+	require.Equal(t, day.Add(25*time.Hour).UnixNano(), points[2].BucketStart)
+
+	// nothing new, nothing folded
+	folded, err = RollUp(ctx, 3)
+	require.NoError(t, err)
+	require.Zero(t, folded)
+
+	// a late event lands in a bucket that was already rolled up
+	record(t, testEvent{at: 5 * time.Minute, hash: "aaa", outcome: events.OutcomeSigned, country: "BR", mobile: true, latency: 200})
+
+	folded, err = RollUp(ctx, 3)
+	require.NoError(t, err)
+	require.Equal(t, 1, folded)
+
+	points, err = Timeseries(ctx, Filter{Granularity: GranularityDay, From: day.UnixNano(), To: day.Add(time.Hour).UnixNano(), Hash: "aaa"}, nil)
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	require.Equal(t, int64(4), points[0].Total)
+	require.Nil(t, points[0].Group)
+
+	top, err := Top(ctx, dayFilter(GranularityDay), DimensionHash, 1)
+	require.NoError(t, err)
+	require.Equal(t, []*Entry{{Value: "aaa", Total: 4, AverageLatencyMicros: 162}}, top)
+
+	top, err = Top(ctx, dayFilter(GranularityMinute), DimensionOutcome, 0)
+	require.NoError(t, err)
+	require.Len(t, top, 2)
+	require.Equal(t, "signed", top[0].Value)
+	require.Equal(t, int64(4), top[0].Total)
+}
+
+func TestShouldCapTheTopLimitAtTheMaximum(t *testing.T) {
+	ctx := context.Background()
+
+	// a week later, away from the events of the other tests
+	week := 7 * 24 * time.Hour
+
+	evs := make([]testEvent, 0, MaxTopLimit+1)
+	for i := range MaxTopLimit + 1 {
+		evs = append(evs, testEvent{at: week + time.Duration(i)*time.Minute, hash: fmt.Sprintf("top-%03d", i), outcome: events.OutcomeUnsigned})
+	}
+	record(t, evs...)
+
+	_, err := RollUp(ctx, 100)
+	require.NoError(t, err)
+
+	filter := Filter{Granularity: GranularityDay, From: day.Add(week).UnixNano(), To: day.Add(week + 24*time.Hour).UnixNano()}
+
+	top, err := Top(ctx, filter, DimensionHash, MaxTopLimit+1)
+	require.NoError(t, err)
+	require.Len(t, top, MaxTopLimit)
+
+	top, err = Top(ctx, filter, DimensionHash, 0)
+	require.NoError(t, err)
+	require.Len(t, top, DefaultTopLimit)
+}
+
+func TestShouldRejectBadQueries(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := Timeseries(ctx, Filter{Granularity: GranularityMinute, From: day.UnixNano(), To: day.AddDate(1, 0, 0).UnixNano()}, nil)
+	require.ErrorIs(t, err, ErrRangeTooLarge)
+
+	_, err = Timeseries(ctx, Filter{Granularity: GranularityHour, From: day.UnixNano(), To: day.UnixNano()}, nil)
+	require.ErrorIs(t, err, ErrInvalidRange)
+
+	_, err = Timeseries(ctx, dayFilter("week"), nil)
+	require.ErrorIs(t, err, ErrUnknownGranularity)
+
+	_, err = Top(ctx, dayFilter(GranularityDay), "id; drop table events", 10)
+	require.ErrorIs(t, err, ErrUnknownDimension)
+
+	_, err = ParseDimensions("hash,,country_code,hash,city")
+	require.ErrorIs(t, err, ErrUnknownDimension)
+
+	dimensions, err := ParseDimensions("hash,,country_code,hash")
+	require.NoError(t, err)
+	require.Equal(t, []Dimension{DimensionHash, DimensionCountryCode}, dimensions)
+}
@@ -0,0 +1,101 @@
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/events"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
+
+type Granularity string
+
+const (
+	GranularityMinute Granularity = "minute"
+	GranularityHour   Granularity = "hour"
+	GranularityDay    Granularity = "day"
+)
+
+// Granularities are all the bucket sizes events are rolled up into.
+var Granularities = []Granularity{GranularityMinute, GranularityHour, GranularityDay}
+
+var ErrUnknownGranularity = errors.New("granularity must be one of minute, hour or day")
+
+func ParseGranularity(granularity string) (Granularity, error) {
+	switch g := Granularity(granularity); g {
+	case "":
+		return GranularityHour, nil
+	case GranularityMinute, GranularityHour, GranularityDay:
+		return g, nil
+	default:
+		return "", fmt.Errorf("%q: %w", granularity, ErrUnknownGranularity)
+	}
+}
+
+// Duration is the width of a bucket, buckets start at multiples of it since the unix epoch, so days are in UTC.
+func (g Granularity) Duration() time.Duration {
+	switch g {
+	case GranularityMinute:
+		return time.Minute
+	case GranularityHour:
+		return time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+type Device string
+
+const (
+	DeviceDesktop Device = "desktop"
+	DeviceMobile  Device = "mobile"
+	DeviceTablet  Device = "tablet"
+	// DeviceUnknown is an event without device headers, e.g. one that didn't come through the CDN
+	DeviceUnknown Device = "unknown"
+)
+
+// Rollup is the number of events of one kind, hash, outcome, country and device within a bucket. Rows only
+// ever grow as events are folded in.
+type Rollup struct {
+	ID          int            `sql:"id"`
+	Granularity Granularity    `sql:"granularity"`
+	BucketStart int64          `sql:"bucket_start"`
+	Kind        events.Kind    `sql:"kind"`
+	Hash        string         `sql:"hash"`
+	Outcome     events.Outcome `sql:"outcome"`
+	CountryCode string         `sql:"country_code"`
+	Device      Device         `sql:"device"`
+	Total       int64          `sql:"total"`
+	// TotalLatencyMicros is the sum of the latencies of the events, for averaging
+	TotalLatencyMicros int64 `sql:"total_latency_us"`
+	CreatedAt          int64 `sql:"created_at"`
+	UpdatedAt          int64 `sql:"updated_at"`
+}
+
+func (r *Rollup) GetID() int {
+	return r.ID
+}
+
+func (r *Rollup) SetID(id int) {
+	r.ID = id
+}
+
+// watermark is the id of the last event folded into the rollups.
+type watermark struct {
+	ID          int    `sql:"id"`
+	Name        string `sql:"name"`
+	LastEventID int    `sql:"last_event_id"`
+	CreatedAt   int64  `sql:"created_at"`
+	UpdatedAt   int64  `sql:"updated_at"`
+}
+
+var watermarkRepository = database.NewRepository[watermark]("rollup_watermarks")
+
+func (w *watermark) GetID() int {
+	return w.ID
+}
+
+func (w *watermark) SetID(id int) {
+	w.ID = id
+}
@@ -0,0 +1,12 @@
+package analytics
+
+import (
+	"github.com/Gustrb/ccanalytics/internal/rest"
+	"github.com/labstack/echo/v5"
+)
+
+func Urls(e *echo.Echo) {
+	// rollups tell who verifies which binaries from where, they are kept behind the token like the events
+	e.GET("/analytics/timeseries", TimeseriesHandler, rest.RequireToken)
+	e.GET("/analytics/top", TopHandler, rest.RequireToken)
+}
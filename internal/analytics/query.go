@@ -0,0 +1,250 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Gustrb/ccanalytics/internal/events"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
+
+// Dimension is a column rollups can be grouped by.
+type Dimension string
+
+const (
+	DimensionKind        Dimension = "kind"
+	DimensionHash        Dimension = "hash"
+	DimensionOutcome     Dimension = "outcome"
+	DimensionCountryCode Dimension = "country_code"
+	DimensionDevice      Dimension = "device"
+)
+
+var Dimensions = []Dimension{DimensionKind, DimensionHash, DimensionOutcome, DimensionCountryCode, DimensionDevice}
+
+const (
+	// MaxBuckets bounds how many buckets a time range may span, so minute buckets can't be asked for over years
+	MaxBuckets = 10000
+
+	DefaultTopLimit = 10
+	MaxTopLimit     = 100
+)
+
+var (
+	ErrUnknownDimension = errors.New("dimension must be one of kind, hash, outcome, country_code or device")
+	ErrInvalidRange     = errors.New("from must be before to")
+	ErrRangeTooLarge    = fmt.Errorf("time range spans more than %d buckets, use a larger granularity", MaxBuckets)
+)
+
+func ParseDimension(dimension string) (Dimension, error) {
+	d := Dimension(dimension)
+	if !slices.Contains(Dimensions, d) {
+		return "", fmt.Errorf("%q: %w", dimension, ErrUnknownDimension)
+	}
+
+	return d, nil
+}
+
+// ParseDimensions parses a comma separated list of dimensions, repeated ones are kept once.
+func ParseDimensions(dimensions string) ([]Dimension, error) {
+	var parsed []Dimension
+
+	for part := range strings.SplitSeq(dimensions, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		d, err := ParseDimension(part)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(parsed, d) {
+			parsed = append(parsed, d)
+		}
+	}
+
+	return parsed, nil
+}
+
+// Filter picks the rollups of one granularity whose buckets start within [From, To), From is moved back to the
+// start of its bucket. Empty fields are ignored.
+type Filter struct {
+	Granularity Granularity
+	From        int64
+	To          int64
+
+	Kind        events.Kind
+	Hash        string
+	Outcome     events.Outcome
+	CountryCode string
+	Device      Device
+}
+
+func (f Filter) where() (string, []any, error) {
+	if !slices.Contains(Granularities, f.Granularity) {
+		return "", nil, fmt.Errorf("%q: %w", f.Granularity, ErrUnknownGranularity)
+	}
+
+	if f.From >= f.To {
+		return "", nil, ErrInvalidRange
+	}
+
+	width := f.Granularity.Duration().Nanoseconds()
+	from := f.From - f.From%width
+
+	if (f.To-from)/width > MaxBuckets {
+		return "", nil, ErrRangeTooLarge
+	}
+
+	conditions := []string{"granularity = ?", "bucket_start >= ?", "bucket_start < ?"}
+	args := []any{f.Granularity, from, f.To}
+
+	equals := func(column, value string) {
+		if value != "" {
+			conditions = append(conditions, column+" = ?")
+			args = append(args, value)
+		}
+	}
+
+	equals("kind", string(f.Kind))
+	equals("hash", f.Hash)
+	equals("outcome", string(f.Outcome))
+	equals("country_code", f.CountryCode)
+	equals("device", string(f.Device))
+
+	return " where " + strings.Join(conditions, " and "), args, nil
+}
+
+// groupRow is a sum of rollups, only the columns it was grouped by are filled in.
+type groupRow struct {
+	BucketStart        int64  `sql:"bucket_start"`
+	Kind               string `sql:"kind"`
+	Hash               string `sql:"hash"`
+	Outcome            string `sql:"outcome"`
+	CountryCode        string `sql:"country_code"`
+	Device             string `sql:"device"`
+	Total              int64  `sql:"total"`
+	TotalLatencyMicros int64  `sql:"total_latency_us"`
+}
+
+func (r *groupRow) value(d Dimension) string {
+	switch d {
+	case DimensionKind:
+		return r.Kind
+	case DimensionHash:
+		return r.Hash
+	case DimensionOutcome:
+		return r.Outcome
+	case DimensionCountryCode:
+		return r.CountryCode
+	default:
+		return r.Device
+	}
+}
+
+func (r *groupRow) averageLatency() int64 {
+	if r.Total == 0 {
+		return 0
+	}
+
+	return r.TotalLatencyMicros / r.Total
+}
+
+// Point is the number of events in one bucket, for one combination of the dimensions asked for.
+type Point struct {
+	BucketStart int64                `json:"bucket_start"`
+	Group       map[Dimension]string `json:"group,omitempty"`
+	Total       int64                `json:"total"`
+	// AverageLatencyMicros is how long the handler took on average, in microseconds
+	AverageLatencyMicros int64 `json:"avg_latency_us"`
+}
+
+// Timeseries sums the rollups per bucket and per combination of groupBy, oldest bucket first. Buckets without
+// events are left out.
+func Timeseries(ctx context.Context, filter Filter, groupBy []Dimension) ([]*Point, error) {
+	where, args, err := filter.where()
+	if err != nil {
+		return nil, err
+	}
+
+	// dimensions end up in the query itself, they can't be anything but column names
+	columns := []string{"bucket_start"}
+	for _, d := range groupBy {
+		if !slices.Contains(Dimensions, d) {
+			return nil, fmt.Errorf("%q: %w", d, ErrUnknownDimension)
+		}
+
+		columns = append(columns, string(d))
+	}
+
+	group := strings.Join(columns, ", ")
+	query := fmt.Sprintf("select %s, sum(total) as total, sum(total_latency_us) as total_latency_us from event_rollups%s group by %s order by %s;", group, where, group, group)
+
+	rows, err := database.SelectContext[groupRow](ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]*Point, 0, len(rows))
+	for _, row := range rows {
+		point := &Point{
+			BucketStart:          row.BucketStart,
+			Total:                row.Total,
+			AverageLatencyMicros: row.averageLatency(),
+		}
+
+		if len(groupBy) > 0 {
+			point.Group = make(map[Dimension]string, len(groupBy))
+			for _, d := range groupBy {
+				point.Group[d] = row.value(d)
+			}
+		}
+
+		points = append(points, point)
+	}
+
+	return points, nil
+}
+
+// Entry is the number of events for one value of a dimension.
+type Entry struct {
+	Value                string `json:"value"`
+	Total                int64  `json:"total"`
+	AverageLatencyMicros int64  `json:"avg_latency_us"`
+}
+
+// Top returns the values of by with the most events over the whole range, most events first.
+func Top(ctx context.Context, filter Filter, by Dimension, limit int) ([]*Entry, error) {
+	where, args, err := filter.where()
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(Dimensions, by) {
+		return nil, fmt.Errorf("%q: %w", by, ErrUnknownDimension)
+	}
+
+	limit = database.ClampLimit(limit, DefaultTopLimit, MaxTopLimit)
+
+	query := fmt.Sprintf("select %[1]s, sum(total) as total, sum(total_latency_us) as total_latency_us from event_rollups%[2]s group by %[1]s order by total desc, %[1]s limit ?;", by, where)
+
+	rows, err := database.SelectContext[groupRow](ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, &Entry{
+			Value:                row.value(by),
+			Total:                row.Total,
+			AverageLatencyMicros: row.averageLatency(),
+		})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,122 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/events"
+	"github.com/Gustrb/ccanalytics/internal/rest"
+	"github.com/labstack/echo/v5"
+)
+
+// defaultRange is how far back from and to look when from is not given.
+const defaultRange = 24 * time.Hour
+
+func TimeseriesHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	filter, err := parseFilter(c)
+	if err != nil {
+		return err
+	}
+
+	groupBy, err := ParseDimensions(c.QueryParam("group_by"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	points, err := Timeseries(ctx, filter, groupBy)
+	if err != nil {
+		return queryError(ctx, err, "timeseries")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"granularity": filter.Granularity,
+		"from":        filter.From,
+		"to":          filter.To,
+		"points":      points,
+	})
+}
+
+func TopHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	filter, err := parseFilter(c)
+	if err != nil {
+		return err
+	}
+
+	by, err := ParseDimension(c.QueryParam("by"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	limit, err := echo.QueryParamOr(c, "limit", DefaultTopLimit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "limit must be an integer")
+	}
+
+	entries, err := Top(ctx, filter, by, limit)
+	if err != nil {
+		return queryError(ctx, err, "top "+string(by))
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"granularity": filter.Granularity,
+		"from":        filter.From,
+		"to":          filter.To,
+		"by":          by,
+		"entries":     entries,
+	})
+}
+
+// parseFilter reads the query parameters both endpoints share, to defaults to now and from to a day before to.
+func parseFilter(c *echo.Context) (Filter, error) {
+	granularity, err := ParseGranularity(c.QueryParam("granularity"))
+	if err != nil {
+		return Filter{}, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	from, err := rest.ParseTimeParam(c.QueryParam("from"))
+	if err != nil {
+		return Filter{}, echo.NewHTTPError(http.StatusBadRequest, "from must be an RFC 3339 date or a unix timestamp in nanoseconds")
+	}
+
+	to, err := rest.ParseTimeParam(c.QueryParam("to"))
+	if err != nil {
+		return Filter{}, echo.NewHTTPError(http.StatusBadRequest, "to must be an RFC 3339 date or a unix timestamp in nanoseconds")
+	}
+
+	if to == 0 {
+		to = time.Now().UnixNano()
+	}
+
+	if from == 0 {
+		from = to - defaultRange.Nanoseconds()
+	}
+
+	return Filter{
+		Granularity: granularity,
+		From:        from,
+		To:          to,
+		Kind:        events.Kind(c.QueryParam("kind")),
+		Hash:        strings.ToLower(c.QueryParam("hash")),
+		Outcome:     events.Outcome(c.QueryParam("outcome")),
+		CountryCode: strings.ToUpper(c.QueryParam("country_code")),
+		Device:      Device(c.QueryParam("device")),
+	}, nil
+}
+
+func queryError(ctx context.Context, err error, query string) error {
+	if errors.Is(err, ErrInvalidRange) || errors.Is(err, ErrRangeTooLarge) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	slog.ErrorContext(ctx, "failed to query rollups", "query", query, "error", err)
+
+	return echo.NewHTTPError(http.StatusInternalServerError, "failed to query "+query)
+}
@@ -0,0 +1,139 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
+
+const (
+	eventsWatermark = "events"
+
+	lastEventIDQuery = "select coalesce(max(id), 0) as id from events;"
+
+	getWatermarkQuery = "select * from rollup_watermarks where name = ?;"
+
+	// tablets report themselves as mobile too on some CDNs, so they are checked first. The device the user agent
+	// tells is only used when the CDN didn't say.
+	aggregateEventsQuery = `select (created_at / ?) * ? as bucket_start, kind, hash, outcome, country_code,
+	case when is_tablet then 'tablet' when is_mobile then 'mobile' when is_desktop then 'desktop'
+	when client_device <> '' then client_device else 'unknown' end as device,
+	count(*) as total, sum(latency_us) as total_latency_us
+	from events where id > ? and id <= ?
+	group by 1, 2, 3, 4, 5, 6;`
+
+	// rows are added to rather than replaced, a bucket is folded into again whenever late events arrive for it
+	addRollupQuery = `insert into event_rollups
+	(granularity, bucket_start, kind, hash, outcome, country_code, device, total, total_latency_us, created_at, updated_at)
+	values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	on conflict (granularity, bucket_start, kind, hash, outcome, country_code, device) do update set
+	total = event_rollups.total + excluded.total,
+	total_latency_us = event_rollups.total_latency_us + excluded.total_latency_us,
+	updated_at = excluded.updated_at;`
+)
+
+// RollUp folds the events written since the last run into the rollups, batchSize events per transaction, until
+// it catches up. It returns how many events were folded in.
+func RollUp(ctx context.Context, batchSize int) (int, error) {
+	batchSize = max(batchSize, 1)
+
+	var total int
+	for {
+		var (
+			folded   int
+			caughtUp bool
+		)
+
+		err := database.RetryOnBusy(ctx, func(ctx context.Context) error {
+			return database.WithinTransaction(ctx, func(ctx context.Context) error {
+				var err error
+				folded, caughtUp, err = rollUpBatch(ctx, batchSize)
+
+				return err
+			})
+		})
+		if err != nil {
+			return total, err
+		}
+
+		total += folded
+
+		if caughtUp {
+			return total, nil
+		}
+	}
+}
+
+// rollUpBatch folds the events after the watermark, up to batchSize ids of them, and moves the watermark past
+// them. Event ids only grow, so the watermark is enough to never count an event twice.
+func rollUpBatch(ctx context.Context, batchSize int) (int, bool, error) {
+	// waits for the writes in flight and keeps new ones out, so no event with an id below the one we are about
+	// to read can still show up once we have read it. It also keeps two api instances from folding the same
+	// events.
+	if err := database.LockTable(ctx, "events"); err != nil {
+		return 0, false, err
+	}
+
+	mark, err := getWatermark(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	last, err := database.SelectContext[struct {
+		ID int `sql:"id"`
+	}](ctx, lastEventIDQuery)
+	if err != nil {
+		return 0, false, err
+	}
+
+	upTo := min(last[0].ID, mark.LastEventID+batchSize)
+	if upTo <= mark.LastEventID {
+		return 0, true, nil
+	}
+
+	var folded int64
+	now := time.Now().UnixNano()
+
+	for _, granularity := range Granularities {
+		width := granularity.Duration().Nanoseconds()
+
+		rollups, err := database.SelectContext[Rollup](ctx, aggregateEventsQuery, width, width, mark.LastEventID, upTo)
+		if err != nil {
+			return 0, false, err
+		}
+
+		folded = 0
+		for _, r := range rollups {
+			_, err := database.ExecContext(ctx, addRollupQuery,
+				granularity, r.BucketStart, r.Kind, r.Hash, r.Outcome, r.CountryCode, r.Device,
+				r.Total, r.TotalLatencyMicros, now, now,
+			)
+			if err != nil {
+				return 0, false, err
+			}
+
+			folded += r.Total
+		}
+	}
+
+	mark.LastEventID = upTo
+	if err := watermarkRepository.Upsert(ctx, mark, "name"); err != nil {
+		return 0, false, err
+	}
+
+	return int(folded), upTo == last[0].ID, nil
+}
+
+func getWatermark(ctx context.Context) (*watermark, error) {
+	marks, err := database.SelectContext[watermark](ctx, getWatermarkQuery, eventsWatermark)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(marks) == 0 {
+		return &watermark{Name: eventsWatermark}, nil
+	}
+
+	return marks[0], nil
+}
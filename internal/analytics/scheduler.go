@@ -0,0 +1,75 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+)
+
+// Scheduler runs RollUp every interval from a goroutine of its own, until Stop is called.
+type Scheduler struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartScheduler rolls up right away and then on every tick, a zero interval starts nothing.
+func StartScheduler(cfg config.AnalyticsConfig) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Scheduler{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	if cfg.RollupInterval <= 0 {
+		close(s.done)
+		return s
+	}
+
+	go s.run(ctx, cfg)
+
+	return s
+}
+
+// Stop cancels the rollup in progress, if any, and waits for the goroutine to exit or ctx to be done. Events
+// left unfolded are picked up by the next run.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.cancel()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for the rollup to stop: %w", ctx.Err())
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, cfg config.AnalyticsConfig) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(cfg.RollupInterval)
+	defer ticker.Stop()
+
+	for {
+		startTime := time.Now()
+
+		folded, err := RollUp(ctx, cfg.RollupBatchSize)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			slog.ErrorContext(ctx, "Failed to roll up events", "folded", folded, "error", err)
+		case folded > 0:
+			slog.InfoContext(ctx, "Rolled up events", "folded", folded, "took", time.Since(startTime))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
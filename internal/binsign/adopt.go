@@ -0,0 +1,58 @@
+package binsign
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/keys"
+)
+
+const (
+	getUnattributedSignedBinariesQuery = "select * from signed_binaries where key_id is null;"
+)
+
+// AdoptUnattributedSignatures links binaries signed before keys were tracked to the given key,
+// only rows whose signature actually verifies against it are touched. It returns how many were adopted,
+// a failure part way through leaves every row as it was.
+func AdoptUnattributedSignatures(ctx context.Context, key *keys.Key) (int, error) {
+	adopted := 0
+
+	err := database.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := database.LockTable(ctx, "signed_binaries"); err != nil {
+			return err
+		}
+
+		signedBinaries, err := database.SelectContext[SignedBinary](ctx, getUnattributedSignedBinariesQuery)
+		if err != nil {
+			return err
+		}
+
+		for _, sb := range signedBinaries {
+			sum, err := hex.DecodeString(sb.Hash)
+			if err != nil {
+				continue
+			}
+
+			if err := key.Verify(sum, sb.Signature); err != nil {
+				continue
+			}
+
+			sb.KeyID = &key.ID
+
+			if err := signedBinaryRepository.Update(ctx, sb); err != nil {
+				return fmt.Errorf("adopting signed binary %s: %w", sb.Hash, err)
+			}
+
+			adopted++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return adopted, nil
+}
@@ -0,0 +1,284 @@
+package binsign
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database/migrator"
+	"github.com/Gustrb/ccanalytics/internal/keys"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	ctx := context.Background()
+
+	dir, err := os.MkdirTemp("", "ccanalytics-binsign-test")
+	if err != nil {
+		slog.Error("Failed to create temp dir", "error", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	cleanup, err := database.Connect(ctx, config.DatabaseConfig{
+		Path:        filepath.Join(dir, "app.db"),
+		JournalMode: "wal",
+		ForeignKeys: true,
+	})
+	if err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		return 1
+	}
+	defer cleanup()
+
+	if err := migrator.MigrateUp(ctx); err != nil {
+		slog.Error("Failed to migrate database", "error", err)
+		return 1
+	}
+
+	if _, err := keys.Rotate(ctx); err != nil {
+		slog.Error("Failed to activate a signing key", "error", err)
+		return 1
+	}
+
+	return m.Run()
+}
+
+func randomContents(t *testing.T) []byte {
+	t.Helper()
+
+	contents := make([]byte, 1024)
+	_, err := rand.Read(contents)
+	require.NoError(t, err)
+
+	return contents
+}
+
+func TestShouldVerifyWhatItSigned(t *testing.T) {
+	ctx := context.Background()
+	contents := randomContents(t)
+
+	verification, err := CheckIfReaderIsSigned(ctx, bytes.NewReader(contents))
+	require.NoError(t, err)
+	require.Equal(t, StatusUnsigned, verification.Status)
+	require.Nil(t, verification.SignedBinary)
+
+	signed, err := SignFile(ctx, bytes.NewReader(contents), Metadata{ArtifactName: "ccanalytics", Version: "1.0.0"})
+	require.NoError(t, err)
+	require.EqualValues(t, len(contents), signed.Size)
+
+	key, err := SigningKey(ctx, signed)
+	require.NoError(t, err)
+
+	sum, err := Digest(bytes.NewReader(contents))
+	require.NoError(t, err)
+	require.NoError(t, keys.Verify(key.PublicKey, sum, signed.Signature))
+
+	verification, err = CheckIfReaderIsSigned(ctx, bytes.NewReader(contents))
+	require.NoError(t, err)
+	require.Equal(t, StatusSigned, verification.Status)
+	require.Equal(t, signed.Hash, verification.SignedBinary.Hash)
+	require.Equal(t, "ccanalytics", verification.SignedBinary.ArtifactName)
+
+	_, err = SignFile(ctx, bytes.NewReader(contents), Metadata{})
+	require.ErrorIs(t, err, ErrDuplicateHash)
+}
+
+func TestShouldRejectATamperedSignature(t *testing.T) {
+	ctx := context.Background()
+	contents := randomContents(t)
+
+	signed, err := SignFile(ctx, bytes.NewReader(contents), Metadata{})
+	require.NoError(t, err)
+
+	// a valid signature, only over something else
+	other, err := SignFile(ctx, bytes.NewReader(randomContents(t)), Metadata{})
+	require.NoError(t, err)
+
+	signed.Signature = other.Signature
+	require.NoError(t, signedBinaryRepository.Update(ctx, signed))
+
+	_, err = CheckIfReaderIsSigned(ctx, bytes.NewReader(contents))
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	sum, err := Digest(bytes.NewReader(contents))
+	require.NoError(t, err)
+
+	_, err = CheckDigest(ctx, sum)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestShouldNotTrustSignaturesOfACompromisedKey(t *testing.T) {
+	ctx := context.Background()
+	contents := randomContents(t)
+
+	key, err := keys.Rotate(ctx)
+	require.NoError(t, err)
+
+	_, err = SignFile(ctx, bytes.NewReader(contents), Metadata{})
+	require.NoError(t, err)
+
+	// rotating away doesn't change anything, the old key's signatures are still good
+	_, err = keys.Rotate(ctx)
+	require.NoError(t, err)
+
+	verification, err := CheckIfReaderIsSigned(ctx, bytes.NewReader(contents))
+	require.NoError(t, err)
+	require.Equal(t, StatusSigned, verification.Status)
+
+	_, err = keys.Retire(ctx, key.Fingerprint, true)
+	require.NoError(t, err)
+
+	verification, err = CheckIfReaderIsSigned(ctx, bytes.NewReader(contents))
+	require.NoError(t, err)
+	require.Equal(t, StatusUntrusted, verification.Status)
+	require.NotNil(t, verification.SignedBinary)
+
+	sum, err := Digest(bytes.NewReader(contents))
+	require.NoError(t, err)
+
+	results, err := CheckDigests(ctx, []CheckItem{{Digest: hex.EncodeToString(sum)}})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, StatusUntrusted, results[0].Status)
+	require.Equal(t, keys.StatusCompromised, results[0].SignedBinary.KeyStatus)
+}
+
+func TestShouldReportRevokedBinaries(t *testing.T) {
+	ctx := context.Background()
+	contents := randomContents(t)
+
+	signed, err := SignFile(ctx, bytes.NewReader(contents), Metadata{})
+	require.NoError(t, err)
+
+	_, err = Revoke(ctx, signed.Hash, "typo")
+	require.ErrorIs(t, err, ErrInvalidRevocationReason)
+
+	_, err = Revoke(ctx, hex.EncodeToString(make([]byte, 32)), ReasonSuperseded)
+	require.ErrorIs(t, err, ErrSignedBinaryNotFound)
+
+	// hashes are stored lowercase, whatever case they are given in
+	revoked, err := Revoke(ctx, strings.ToUpper(signed.Hash), ReasonCompromisedBuild)
+	require.NoError(t, err)
+	require.True(t, revoked.IsRevoked())
+	require.Equal(t, ReasonCompromisedBuild, revoked.RevocationReason)
+
+	_, err = Revoke(ctx, signed.Hash, ReasonSuperseded)
+	require.ErrorIs(t, err, ErrAlreadyRevoked)
+
+	verification, err := CheckIfReaderIsSigned(ctx, bytes.NewReader(contents))
+	require.NoError(t, err)
+	require.Equal(t, StatusRevoked, verification.Status)
+	require.Equal(t, revoked.RevokedAt, verification.SignedBinary.RevokedAt)
+	require.Equal(t, ReasonCompromisedBuild, verification.SignedBinary.RevocationReason)
+
+	list, err := BuildRevocationList(ctx)
+	require.NoError(t, err)
+	require.Contains(t, list.Entries, RevocationEntry{Hash: signed.Hash, Reason: ReasonCompromisedBuild, RevokedAt: revoked.RevokedAt})
+}
+
+func TestShouldLetOnlyOneOfConcurrentRevocationsSucceed(t *testing.T) {
+	ctx := context.Background()
+
+	signed, err := SignFile(ctx, bytes.NewReader(randomContents(t)), Metadata{})
+	require.NoError(t, err)
+
+	// both saw the binary as not revoked yet, only the first update may go through
+	_, err = database.ExecContext(ctx, revokeSignedBinaryQuery, 1, ReasonSuperseded, 1, signed.ID)
+	require.NoError(t, err)
+
+	result, err := database.ExecContext(ctx, revokeSignedBinaryQuery, 2, ReasonSignedInError, 2, signed.ID)
+	require.NoError(t, err)
+
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	require.Zero(t, affected)
+
+	got, err := GetSignedBinaryByHash(ctx, signed.Hash)
+	require.NoError(t, err)
+	require.Equal(t, ReasonSuperseded, got.RevocationReason)
+}
+
+func TestShouldCapTheListLimitAtTheMaximum(t *testing.T) {
+	ctx := context.Background()
+
+	// signing them one by one would take a while, only listing them matters here
+	err := database.WithinTransaction(ctx, func(ctx context.Context) error {
+		for i := range MaxListLimit + 1 {
+			_, err := database.ExecContext(ctx, "insert into signed_binaries (hash, artifact_name, created_at, updated_at) values (?, ?, ?, ?);", fmt.Sprintf("list-limit-%04d", i), "list-limit", i+1, i+1)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	page, err := List(ctx, ListFilter{ArtifactName: "list-limit", Limit: MaxListLimit + 1})
+	require.NoError(t, err)
+	require.Len(t, page.Items, MaxListLimit)
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = List(ctx, ListFilter{ArtifactName: "list-limit"})
+	require.NoError(t, err)
+	require.Len(t, page.Items, DefaultListLimit)
+	require.NotEmpty(t, page.NextCursor)
+}
+
+func TestShouldReportBinariesRegisteredBeforeSignaturesAsLegacy(t *testing.T) {
+	ctx := context.Background()
+	contents := randomContents(t)
+
+	sum, err := Digest(bytes.NewReader(contents))
+	require.NoError(t, err)
+
+	hash := hex.EncodeToString(sum)
+
+	// what the baseline wrote, the signature and key columns were added after it
+	_, err = database.ExecContext(ctx, "insert into signed_binaries (hash, created_at, updated_at) values (?, ?, ?);", hash, 1, 1)
+	require.NoError(t, err)
+
+	verification, err := CheckIfReaderIsSigned(ctx, bytes.NewReader(contents))
+	require.NoError(t, err)
+	require.Equal(t, StatusLegacy, verification.Status)
+	require.True(t, verification.SignedBinary.IsLegacy())
+
+	results, err := CheckDigests(ctx, []CheckItem{{Digest: hash}})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Empty(t, results[0].Error)
+	require.Equal(t, StatusLegacy, results[0].Status)
+	require.Empty(t, results[0].SignedBinary.KeyID)
+
+	// nothing to adopt, there is no signature to verify
+	key, err := keys.GetActive(ctx)
+	require.NoError(t, err)
+
+	_, err = AdoptUnattributedSignatures(ctx, key)
+	require.NoError(t, err)
+
+	verification, err = CheckDigest(ctx, sum)
+	require.NoError(t, err)
+	require.Equal(t, StatusLegacy, verification.Status)
+
+	_, err = Revoke(ctx, hash, ReasonSuperseded)
+	require.NoError(t, err)
+
+	verification, err = CheckDigest(ctx, sum)
+	require.NoError(t, err)
+	require.Equal(t, StatusRevoked, verification.Status)
+}
@@ -0,0 +1,104 @@
+package binsign
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/Gustrb/ccanalytics/internal/keys"
+)
+
+const (
+	DigestAlgorithmSHA256 = "sha256"
+
+	// MaxCheckBatchSize bounds how many digests a single check request may carry
+	MaxCheckBatchSize = 100
+)
+
+var (
+	ErrUnsupportedDigestAlgorithm = errors.New("unsupported digest algorithm, only sha256 is supported")
+	ErrInvalidDigest              = errors.New("digest must be the hex encoding of a sha256 sum")
+)
+
+type CheckItem struct {
+	Digest    string `json:"digest"`
+	Algorithm string `json:"algorithm"`
+}
+
+// CheckResult is the outcome of checking a single digest, Error is set instead of Status when the item could not be checked.
+type CheckResult struct {
+	Digest       string            `json:"digest"`
+	Algorithm    string            `json:"algorithm"`
+	Status       Status            `json:"status,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	SignedBinary *SignedBinaryView `json:"signed_binary,omitempty"`
+}
+
+// ParseDigest validates a hex encoded digest, an empty algorithm defaults to sha256.
+func ParseDigest(digest, algorithm string) ([]byte, error) {
+	if algorithm != "" && !strings.EqualFold(algorithm, DigestAlgorithmSHA256) {
+		return nil, ErrUnsupportedDigestAlgorithm
+	}
+
+	sum, err := hex.DecodeString(strings.ToLower(digest))
+	if err != nil || len(sum) != sha256.Size {
+		return nil, ErrInvalidDigest
+	}
+
+	return sum, nil
+}
+
+// CheckDigests checks every item independently, a failing item never fails the whole batch.
+func CheckDigests(ctx context.Context, items []CheckItem) ([]CheckResult, error) {
+	results := make([]CheckResult, 0, len(items))
+
+	for _, item := range items {
+		result := CheckResult{
+			Digest:    strings.ToLower(item.Digest),
+			Algorithm: DigestAlgorithmSHA256,
+		}
+
+		if item.Algorithm != "" {
+			result.Algorithm = strings.ToLower(item.Algorithm)
+		}
+
+		sum, err := ParseDigest(item.Digest, item.Algorithm)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+
+			continue
+		}
+
+		verification, err := CheckDigest(ctx, sum)
+		switch {
+		case errors.Is(err, ErrInvalidSignature), errors.Is(err, ErrUnknownKey):
+			slog.WarnContext(ctx, "Digest signature could not be verified", "digest", result.Digest, "error", err)
+			result.Error = err.Error()
+		case err != nil:
+			return nil, err
+		default:
+			result.Status = verification.Status
+
+			if sb := verification.SignedBinary; sb != nil {
+				var key *keys.Key
+				if !sb.IsLegacy() {
+					key, err = SigningKey(ctx, sb)
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				view := NewSignedBinaryView(sb, key)
+				result.SignedBinary = &view
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
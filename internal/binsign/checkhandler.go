@@ -0,0 +1,69 @@
+package binsign
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/Gustrb/ccanalytics/internal/events"
+	"github.com/labstack/echo/v5"
+)
+
+type checkRequest struct {
+	// Digest and Algorithm allow checking a single digest without wrapping it in Items
+	Digest    string      `json:"digest"`
+	Algorithm string      `json:"algorithm"`
+	Items     []CheckItem `json:"items"`
+}
+
+func CheckHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req checkRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	items := req.Items
+	if req.Digest != "" {
+		items = append([]CheckItem{{Digest: req.Digest, Algorithm: req.Algorithm}}, items...)
+	}
+
+	if len(items) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "at least one digest is required")
+	}
+
+	if len(items) > MaxCheckBatchSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d digests can be checked per request", MaxCheckBatchSize))
+	}
+
+	results, err := CheckDigests(ctx, items)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to check digests", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to check digests")
+	}
+
+	for _, result := range results {
+		annotateResult(ctx, result)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"results":        results,
+		"max_batch_size": MaxCheckBatchSize,
+	})
+}
+
+// annotateResult records a digest of the batch as if it had been sent to checksign on its own.
+func annotateResult(ctx context.Context, result CheckResult) {
+	switch {
+	case result.Status != "":
+		// statuses and outcomes share their names
+		events.AnnotateItem(ctx, result.Digest, events.Outcome(result.Status))
+	case result.Error == ErrInvalidDigest.Error() || result.Error == ErrUnsupportedDigestAlgorithm.Error():
+		// whatever was sent is not a hash worth keeping
+		events.AnnotateItem(ctx, "", events.OutcomeRejected)
+	default:
+		events.AnnotateItem(ctx, result.Digest, events.OutcomeInvalid)
+	}
+}
@@ -1,9 +1,14 @@
 package binsign
 
 import (
+	"encoding/hex"
+	"errors"
 	"log/slog"
 	"net/http"
 
+	"github.com/Gustrb/ccanalytics/internal/events"
+	"github.com/Gustrb/ccanalytics/internal/keys"
+	"github.com/Gustrb/ccanalytics/internal/tlog"
 	"github.com/labstack/echo/v5"
 )
 
@@ -23,20 +28,109 @@ func CheckSignHandler(c *echo.Context) error {
 	}
 	defer fileHandle.Close()
 
-	signedFile, err := CheckIfReaderIsSigned(ctx, fileHandle)
+	sum, err := Digest(fileHandle)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to hash file", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to check if file is signed")
+	}
+
+	hash := hex.EncodeToString(sum)
+
+	verification, err := CheckDigest(ctx, sum)
+	if errors.Is(err, ErrInvalidSignature) || errors.Is(err, ErrUnknownKey) {
+		events.Annotate(ctx, hash, events.OutcomeInvalid)
+		slog.WarnContext(ctx, "file signature is invalid", "file_name", fheader.Filename, "error", err)
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "file signature is invalid")
+	}
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to check if file is signed", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to check if file is signed")
 	}
 
-	if signedFile == nil {
+	// statuses and outcomes share their names
+	events.Annotate(ctx, hash, events.Outcome(verification.Status))
+
+	if verification.Status == StatusUnsigned {
 		// 404
 		slog.InfoContext(ctx, "file is not signed", "file_name", fheader.Filename)
 		return echo.NewHTTPError(http.StatusNotFound, "file is not signed")
 	}
 
-	return c.JSON(http.StatusOK, map[string]any{
+	signedFile := verification.SignedBinary
+
+	var key *keys.Key
+	if !signedFile.IsLegacy() {
+		key, err = SigningKey(ctx, signedFile)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to get signing key", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to check if file is signed")
+		}
+	}
+
+	response := map[string]any{
+		"status":    verification.Status,
 		"file_name": fheader.Filename,
 		"signed_at": signedFile.CreatedAt,
-	})
+		"hash":      signedFile.Hash,
+		"signature": signedFile.Signature,
+
+		"artifact_name":     signedFile.ArtifactName,
+		"version":           signedFile.Version,
+		"os":                signedFile.OS,
+		"arch":              signedFile.Arch,
+		"original_filename": signedFile.Filename,
+		"size":              signedFile.Size,
+		"uploader":          signedFile.Uploader,
+		"labels":            signedFile.Labels,
+	}
+
+	if key != nil {
+		response["algorithm"] = key.Algorithm
+		response["key_id"] = key.Fingerprint
+		response["key_status"] = key.Status
+	}
+
+	includeProof, err := echo.QueryParamOr(c, "include_proof", false)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "include_proof must be a boolean")
+	}
+
+	if includeProof {
+		proof, err := tlog.ProveInclusion(ctx, signedFile.Hash, 0)
+		switch {
+		case errors.Is(err, tlog.ErrLeafNotFound):
+			// signed before the transparency log existed
+			slog.WarnContext(ctx, "signed binary is not in the transparency log", "hash", signedFile.Hash)
+		case err != nil:
+			slog.ErrorContext(ctx, "failed to build inclusion proof", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to build inclusion proof")
+		default:
+			response["inclusion_proof"] = proof
+		}
+	}
+
+	if verification.Status == StatusRevoked {
+		// 410, the file was signed once but must not be trusted anymore
+		slog.InfoContext(ctx, "file signature has been revoked", "file_name", fheader.Filename, "reason", signedFile.RevocationReason)
+
+		response["revoked_at"] = signedFile.RevokedAt
+		response["revocation_reason"] = signedFile.RevocationReason
+
+		return c.JSON(http.StatusGone, response)
+	}
+
+	if verification.Status == StatusLegacy {
+		slog.InfoContext(ctx, "file was registered before signatures were recorded", "file_name", fheader.Filename)
+
+		response["message"] = "file was registered before signatures were recorded, there is no signature to verify"
+	}
+
+	if verification.Status == StatusUntrusted {
+		// 403, the signature verifies but the key that made it leaked
+		slog.WarnContext(ctx, "file was signed by a compromised key", "file_name", fheader.Filename, "key_id", key.Fingerprint)
+
+		return c.JSON(http.StatusForbidden, response)
+	}
+
+	return c.JSON(http.StatusOK, response)
 }
@@ -5,24 +5,52 @@ import (
 	"errors"
 
 	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
-)
-
-const (
-	insertSignedBinaryQuery = "insert into signed_binaries (hash, created_at, updated_at) values (?, ?, ?);"
+	"github.com/Gustrb/ccanalytics/internal/tlog"
 )
 
 var (
 	ErrDuplicateHash = errors.New("a signed binary with the same hash already exists")
 )
 
+// Create stores the signed binary and records the signing event in the transparency log,
+// either both happen or neither does. It is retried while another process holds the database lock.
 func Create(ctx context.Context, sb *SignedBinary) (*SignedBinary, error) {
-	if err := database.InsertContext(ctx, insertSignedBinaryQuery, sb); err != nil {
-		if database.IsDuplicateEntryError(err) {
-			return nil, ErrDuplicateHash
-		}
-
+	err := database.RetryOnBusy(ctx, func(ctx context.Context) error {
+		return create(ctx, sb)
+	})
+	if err != nil {
+		// the insert was rolled back, the id it got is meaningless
+		sb.ID = 0
 		return nil, err
 	}
 
 	return sb, nil
 }
+
+func create(ctx context.Context, sb *SignedBinary) error {
+	return database.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := signedBinaryRepository.Insert(ctx, sb); err != nil {
+			if errors.Is(err, database.ErrUniqueViolation) {
+				return ErrDuplicateHash
+			}
+
+			return err
+		}
+
+		key, err := SigningKey(ctx, sb)
+		if err != nil {
+			return err
+		}
+
+		leaf := tlog.NewLeaf(
+			tlog.WithHash(sb.Hash),
+			tlog.WithKeyID(key.Fingerprint),
+			tlog.WithTimestamp(sb.CreatedAt),
+		)
+		if _, err := tlog.Append(ctx, leaf); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
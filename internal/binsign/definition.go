@@ -1,12 +1,43 @@
 package binsign
 
-import "time"
+import (
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
 
 type SignedBinary struct {
 	ID        int    `sql:"id"`
 	Hash      string `sql:"hash"`
+	Signature string `sql:"signature"`
+	KeyID     *int   `sql:"key_id"`
 	CreatedAt int64  `sql:"created_at"`
 	UpdatedAt int64  `sql:"updated_at"`
+
+	RevokedAt        int64            `sql:"revoked_at"`
+	RevocationReason RevocationReason `sql:"revocation_reason"`
+
+	ArtifactName string `sql:"artifact_name"`
+	Version      string `sql:"version"`
+	OS           string `sql:"os"`
+	Arch         string `sql:"arch"`
+	Filename     string `sql:"filename"`
+	Size         int64  `sql:"size"`
+	Uploader     string `sql:"uploader"`
+	Labels       Labels `sql:"labels"`
+}
+
+var signedBinaryRepository = database.NewRepository[SignedBinary]("signed_binaries")
+
+// Metadata describes which release a signed binary belongs to, it is supplied by whoever signs the file.
+type Metadata struct {
+	ArtifactName string
+	Version      string
+	OS           string
+	Arch         string
+	Filename     string
+	Uploader     string
+	Labels       Labels
 }
 
 func (sb *SignedBinary) GetID() int {
@@ -17,6 +48,19 @@ func (sb *SignedBinary) SetID(id int) {
 	sb.ID = id
 }
 
+func (sb *SignedBinary) GetCreatedAt() int64 {
+	return sb.CreatedAt
+}
+
+func (sb *SignedBinary) IsRevoked() bool {
+	return sb.RevokedAt != 0
+}
+
+// IsLegacy is true for binaries registered before signatures were recorded, they have neither a signature nor a key.
+func (sb *SignedBinary) IsLegacy() bool {
+	return sb.Signature == "" && sb.KeyID == nil
+}
+
 type SignedBinaryOptions func(*SignedBinary)
 
 func WithHash(hash string) SignedBinaryOptions {
@@ -25,8 +69,40 @@ func WithHash(hash string) SignedBinaryOptions {
 	}
 }
 
+func WithSignature(signature string) SignedBinaryOptions {
+	return func(sb *SignedBinary) {
+		sb.Signature = signature
+	}
+}
+
+func WithKeyID(keyID int) SignedBinaryOptions {
+	return func(sb *SignedBinary) {
+		sb.KeyID = &keyID
+	}
+}
+
+func WithMetadata(metadata Metadata) SignedBinaryOptions {
+	return func(sb *SignedBinary) {
+		sb.ArtifactName = metadata.ArtifactName
+		sb.Version = metadata.Version
+		sb.OS = metadata.OS
+		sb.Arch = metadata.Arch
+		sb.Filename = metadata.Filename
+		sb.Uploader = metadata.Uploader
+		sb.Labels = metadata.Labels
+	}
+}
+
+func WithSize(size int64) SignedBinaryOptions {
+	return func(sb *SignedBinary) {
+		sb.Size = size
+	}
+}
+
 func NewSignedBinary(opts ...SignedBinaryOptions) *SignedBinary {
-	m := &SignedBinary{}
+	m := &SignedBinary{
+		Labels: Labels{},
+	}
 
 	for _, opt := range opts {
 		opt(m)
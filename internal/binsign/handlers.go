@@ -1,10 +1,17 @@
 package binsign
 
 import (
+	"github.com/Gustrb/ccanalytics/internal/events"
+	"github.com/Gustrb/ccanalytics/internal/rest"
 	"github.com/labstack/echo/v5"
 )
 
 func Urls(e *echo.Echo) {
-	e.POST("/binsign/sign", SignHandler)
-	e.POST("/binsign/checksign", CheckSignHandler)
+	e.POST("/binsign/sign", SignHandler, events.Track(events.KindSign), rest.RequireToken)
+	e.POST("/binsign/checksign", CheckSignHandler, events.Track(events.KindCheckSign))
+	e.POST("/binsign/check", CheckHandler, events.Track(events.KindCheckSign))
+	e.POST("/binsign/revoke", RevokeHandler, rest.RequireToken)
+	e.GET("/binsign/revocations", RevocationListHandler)
+	e.GET("/binsign/binaries", ListHandler)
+	e.GET("/binsign/binaries/:hash", GetHandler)
 }
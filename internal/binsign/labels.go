@@ -0,0 +1,75 @@
+package binsign
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	labelKeyReg = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+)
+
+var (
+	ErrInvalidLabel = errors.New("labels must look like key=value, with keys made of letters, digits, '_', '.' or '-'")
+)
+
+// Labels are free-form key/value pairs attached to a signed binary, stored as a JSON object.
+type Labels map[string]string
+
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+
+	data, err := json.Marshal(map[string]string(l))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+func (l *Labels) Scan(src any) error {
+	var data []byte
+
+	switch v := src.(type) {
+	case nil:
+		*l = Labels{}
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("scanning labels: unexpected type %T", src)
+	}
+
+	labels := Labels{}
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return fmt.Errorf("scanning labels: %w", err)
+	}
+
+	*l = labels
+
+	return nil
+}
+
+// ParseLabels turns a list of key=value pairs into Labels.
+func ParseLabels(pairs []string) (Labels, error) {
+	labels := Labels{}
+
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || !labelKeyReg.MatchString(key) {
+			return nil, fmt.Errorf("%q: %w", pair, ErrInvalidLabel)
+		}
+
+		labels[key] = value
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,110 @@
+package binsign
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
+
+const (
+	listSignedBinariesQuery = "select * from signed_binaries"
+
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+var (
+	hashPrefixReg = regexp.MustCompile(`^[0-9a-f]{1,64}$`)
+)
+
+var (
+	ErrInvalidHashPrefix = errors.New("hash prefix must be lowercase hex")
+)
+
+// ListFilter narrows down signed binaries by their metadata, empty fields are ignored.
+type ListFilter struct {
+	ArtifactName string
+	Version      string
+	OS           string
+	Arch         string
+	Filename     string
+	Uploader     string
+	Labels       Labels
+
+	HashPrefix    string
+	CreatedAfter  int64
+	CreatedBefore int64
+
+	Cursor string
+	Limit  int
+	Order  database.SortOrder
+}
+
+func (f ListFilter) where() ([]string, []any, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+
+	add := func(condition string, value any) {
+		conditions = append(conditions, condition)
+		args = append(args, value)
+	}
+
+	equals := func(column, value string) {
+		if value != "" {
+			add(column+" = ?", value)
+		}
+	}
+
+	equals("artifact_name", f.ArtifactName)
+	equals("version", f.Version)
+	equals("os", f.OS)
+	equals("arch", f.Arch)
+	equals("filename", f.Filename)
+	equals("uploader", f.Uploader)
+
+	for key, value := range f.Labels {
+		field, arg := database.JSONTextField("labels", key)
+		conditions = append(conditions, field+" = ?")
+		args = append(args, arg, value)
+	}
+
+	if f.HashPrefix != "" {
+		prefix := strings.ToLower(f.HashPrefix)
+		if !hashPrefixReg.MatchString(prefix) {
+			return nil, nil, ErrInvalidHashPrefix
+		}
+
+		add("hash like ?", prefix+"%")
+	}
+
+	if f.CreatedAfter != 0 {
+		add("created_at >= ?", f.CreatedAfter)
+	}
+
+	if f.CreatedBefore != 0 {
+		add("created_at < ?", f.CreatedBefore)
+	}
+
+	return conditions, args, nil
+}
+
+func List(ctx context.Context, filter ListFilter) (*database.Page[SignedBinary], error) {
+	conditions, args, err := filter.where()
+	if err != nil {
+		return nil, err
+	}
+
+	return database.SelectPageContext[SignedBinary](ctx, database.PageQuery{
+		Query:  listSignedBinariesQuery,
+		Where:  conditions,
+		Args:   args,
+		Cursor: filter.Cursor,
+		Limit:  database.ClampLimit(filter.Limit, DefaultListLimit, MaxListLimit),
+		Order:  filter.Order,
+	})
+}
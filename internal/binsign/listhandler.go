@@ -0,0 +1,126 @@
+package binsign
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/keys"
+	"github.com/Gustrb/ccanalytics/internal/rest"
+	"github.com/labstack/echo/v5"
+)
+
+func ListHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	labels, err := ParseLabels(c.QueryParams()["label"])
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	limit, err := echo.QueryParamOr(c, "limit", DefaultListLimit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "limit must be an integer")
+	}
+
+	order, err := database.ParseSortOrder(c.QueryParam("order"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	createdAfter, err := rest.ParseTimeParam(c.QueryParam("created_after"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "created_after must be an RFC 3339 date or a unix timestamp in nanoseconds")
+	}
+
+	createdBefore, err := rest.ParseTimeParam(c.QueryParam("created_before"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "created_before must be an RFC 3339 date or a unix timestamp in nanoseconds")
+	}
+
+	page, err := List(ctx, ListFilter{
+		ArtifactName:  c.QueryParam("artifact_name"),
+		Version:       c.QueryParam("version"),
+		OS:            c.QueryParam("os"),
+		Arch:          c.QueryParam("arch"),
+		Filename:      c.QueryParam("original_filename"),
+		Uploader:      c.QueryParam("uploader"),
+		Labels:        labels,
+		HashPrefix:    c.QueryParam("hash_prefix"),
+		CreatedAfter:  createdAfter,
+		CreatedBefore: createdBefore,
+		Cursor:        c.QueryParam("cursor"),
+		Limit:         limit,
+		Order:         order,
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidHashPrefix) || errors.Is(err, database.ErrInvalidCursor) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		slog.ErrorContext(ctx, "failed to list signed binaries", "error", err)
+
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list signed binaries")
+	}
+
+	views, err := renderSignedBinaries(c, page.Items)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"binaries":    views,
+		"next_cursor": page.NextCursor,
+	})
+}
+
+func GetHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	// hashes are stored lowercase, as hex.EncodeToString writes them
+	signedBinary, err := GetSignedBinaryByHash(ctx, strings.ToLower(c.Param("hash")))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get signed binary", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get signed binary")
+	}
+
+	if signedBinary == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "signed binary not found")
+	}
+
+	views, err := renderSignedBinaries(c, []*SignedBinary{signedBinary})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, views[0])
+}
+
+func renderSignedBinaries(c *echo.Context, signedBinaries []*SignedBinary) ([]SignedBinaryView, error) {
+	ctx := c.Request().Context()
+
+	keyList, err := keys.List(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list keys", "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to list signed binaries")
+	}
+
+	keysByID := make(map[int]*keys.Key, len(keyList))
+	for _, k := range keyList {
+		keysByID[k.ID] = k
+	}
+
+	views := make([]SignedBinaryView, 0, len(signedBinaries))
+	for _, sb := range signedBinaries {
+		var key *keys.Key
+		if sb.KeyID != nil {
+			key = keysByID[*sb.KeyID]
+		}
+
+		views = append(views, NewSignedBinaryView(sb, key))
+	}
+
+	return views, nil
+}
@@ -0,0 +1,74 @@
+package binsign
+
+import (
+	"context"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/keys"
+)
+
+const (
+	RevocationListVersion = 1
+
+	// revocationListValidity is a hint to offline verifiers on how often they should refresh the list
+	revocationListValidity = 24 * time.Hour
+)
+
+const (
+	getRevokedSignedBinariesQuery = "select * from signed_binaries where revoked_at != 0 order by revoked_at;"
+)
+
+type RevocationEntry struct {
+	Hash      string           `json:"hash"`
+	Reason    RevocationReason `json:"reason"`
+	RevokedAt int64            `json:"revoked_at"`
+}
+
+type RevocationList struct {
+	Version     int               `json:"version"`
+	GeneratedAt int64             `json:"generated_at"`
+	NextUpdate  int64             `json:"next_update"`
+	Entries     []RevocationEntry `json:"entries"`
+}
+
+func GetRevokedSignedBinaries(ctx context.Context) ([]*SignedBinary, error) {
+	return database.SelectContext[SignedBinary](ctx, getRevokedSignedBinariesQuery)
+}
+
+func BuildRevocationList(ctx context.Context) (*RevocationList, error) {
+	revoked, err := GetRevokedSignedBinaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	list := &RevocationList{
+		Version:     RevocationListVersion,
+		GeneratedAt: now.UnixNano(),
+		NextUpdate:  now.Add(revocationListValidity).UnixNano(),
+		Entries:     make([]RevocationEntry, 0, len(revoked)),
+	}
+
+	for _, sb := range revoked {
+		list.Entries = append(list.Entries, RevocationEntry{
+			Hash:      sb.Hash,
+			Reason:    sb.RevocationReason,
+			RevokedAt: sb.RevokedAt,
+		})
+	}
+
+	return list, nil
+}
+
+// SignedRevocationList builds the current revocation list and seals it with the active key, verifiers open it
+// against the keys they pinned, see keys.Envelope.Open.
+func SignedRevocationList(ctx context.Context) (*keys.Envelope, error) {
+	list, err := BuildRevocationList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys.Seal(ctx, list)
+}
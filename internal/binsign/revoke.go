@@ -0,0 +1,142 @@
+package binsign
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
+
+type RevocationReason string
+
+const (
+	ReasonUnspecified          RevocationReason = "unspecified"
+	ReasonKeyCompromise        RevocationReason = "key_compromise"
+	ReasonSuperseded           RevocationReason = "superseded"
+	ReasonCessationOfOperation RevocationReason = "cessation_of_operation"
+	ReasonCompromisedBuild     RevocationReason = "compromised_build"
+	ReasonSignedInError        RevocationReason = "signed_in_error"
+)
+
+const (
+	// only a binary that isn't revoked yet is updated, whichever of two concurrent revocations comes second
+	// affects no row
+	revokeSignedBinaryQuery = "update signed_binaries set revoked_at = ?, revocation_reason = ?, updated_at = ? where id = ? and revoked_at = 0;"
+)
+
+var (
+	RevocationReasons = []RevocationReason{
+		ReasonUnspecified,
+		ReasonKeyCompromise,
+		ReasonSuperseded,
+		ReasonCessationOfOperation,
+		ReasonCompromisedBuild,
+		ReasonSignedInError,
+	}
+)
+
+var (
+	ErrSignedBinaryNotFound    = errors.New("signed binary not found")
+	ErrAlreadyRevoked          = errors.New("signed binary has already been revoked")
+	ErrInvalidRevocationReason = errors.New("invalid revocation reason")
+	ErrHashRequired            = errors.New("hash is required to revoke a signed binary")
+)
+
+func ParseRevocationReason(reason string) (RevocationReason, error) {
+	if reason == "" {
+		return ReasonUnspecified, nil
+	}
+
+	r := RevocationReason(reason)
+	if !slices.Contains(RevocationReasons, r) {
+		return "", fmt.Errorf("%q: %w", reason, ErrInvalidRevocationReason)
+	}
+
+	return r, nil
+}
+
+func RevokeFileAt(ctx context.Context, filePath string, reason RevocationReason) (*SignedBinary, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sum, err := digest(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return Revoke(ctx, hex.EncodeToString(sum), reason)
+}
+
+// Revoke marks the signed binary with the given hash as revoked, the row is kept so verifiers can tell
+// a revoked binary apart from one that was never signed.
+func Revoke(ctx context.Context, hash string, reason RevocationReason) (*SignedBinary, error) {
+	if hash == "" {
+		return nil, ErrHashRequired
+	}
+
+	if !slices.Contains(RevocationReasons, reason) {
+		return nil, fmt.Errorf("%q: %w", reason, ErrInvalidRevocationReason)
+	}
+
+	hash = strings.ToLower(hash)
+
+	var signedBinary *SignedBinary
+
+	err := database.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := database.LockTable(ctx, "signed_binaries"); err != nil {
+			return err
+		}
+
+		sb, err := GetSignedBinaryByHash(ctx, hash)
+		if err != nil {
+			return err
+		}
+
+		if sb == nil {
+			return ErrSignedBinaryNotFound
+		}
+
+		if sb.IsRevoked() {
+			return ErrAlreadyRevoked
+		}
+
+		now := time.Now().UnixNano()
+
+		result, err := database.ExecContext(ctx, revokeSignedBinaryQuery, now, reason, now, sb.ID)
+		if err != nil {
+			return fmt.Errorf("revoking signed binary %s: %w", hash, err)
+		}
+
+		revoked, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("revoking signed binary %s: %w", hash, err)
+		}
+
+		// someone else revoked it between the lookup and the update
+		if revoked == 0 {
+			return ErrAlreadyRevoked
+		}
+
+		sb.RevokedAt = now
+		sb.RevocationReason = reason
+		sb.UpdatedAt = now
+
+		signedBinary = sb
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return signedBinary, nil
+}
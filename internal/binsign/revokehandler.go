@@ -0,0 +1,65 @@
+package binsign
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+)
+
+type revokeRequest struct {
+	Hash   string `json:"hash"`
+	Reason string `json:"reason"`
+}
+
+func RevokeHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req revokeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	reason, err := ParseRevocationReason(req.Reason)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid revocation reason")
+	}
+
+	signedBinary, err := Revoke(ctx, req.Hash, reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrHashRequired):
+			return echo.NewHTTPError(http.StatusBadRequest, "hash is required")
+		case errors.Is(err, ErrSignedBinaryNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "signed binary not found")
+		case errors.Is(err, ErrAlreadyRevoked):
+			return echo.NewHTTPError(http.StatusConflict, "signed binary has already been revoked")
+		}
+
+		slog.ErrorContext(ctx, "failed to revoke signed binary", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke signed binary")
+	}
+
+	slog.InfoContext(ctx, "signed binary revoked", "hash", signedBinary.Hash, "reason", signedBinary.RevocationReason)
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"hash":              signedBinary.Hash,
+		"revoked_at":        signedBinary.RevokedAt,
+		"revocation_reason": signedBinary.RevocationReason,
+	})
+}
+
+func RevocationListHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	envelope, err := SignedRevocationList(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to build revocation list", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to build revocation list")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="revocations.json"`)
+
+	return c.JSON(http.StatusOK, envelope)
+}
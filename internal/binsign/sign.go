@@ -4,45 +4,87 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"os"
-	"sync"
+	"path/filepath"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/keys"
 )
 
-// Note: crazy early optimization, maybe it is a silly one. But I wanted to use sync.Pools. Remove if it doesn't make a difference.
-var bufferPool = sync.Pool{
-	New: func() any {
-		return make([]byte, 32) // SHA-256 produces a 32-byte hash
-	},
-}
+var (
+	ErrInvalidSignature = errors.New("signature does not match the binary digest")
+	ErrUnknownKey       = errors.New("binary was signed with a key that is not known")
+)
 
-func SignFileAt(ctx context.Context, filePath string) error {
+func SignFileAt(ctx context.Context, filePath string, metadata Metadata) (*SignedBinary, error) {
 	f, err := os.Open(filePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer f.Close()
 
-	return SignFile(ctx, f)
+	if metadata.Filename == "" {
+		metadata.Filename = filepath.Base(filePath)
+	}
+
+	return SignFile(ctx, f, metadata)
 }
 
-func SignFile(ctx context.Context, reader io.Reader) error {
-	hash, err := digest(reader)
+// SignFile produces a detached Ed25519 signature over the raw SHA-256 digest using the active key,
+// so verifiers only need the public key and the file.
+func SignFile(ctx context.Context, reader io.Reader, metadata Metadata) (*SignedBinary, error) {
+	counter := &countingReader{reader: reader}
+
+	sum, err := digest(counter)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	signedBinary := NewSignedBinary(
-		WithHash(hash),
-	)
-	if _, err := Create(ctx, signedBinary); err != nil {
-		return err
+	var signedBinary *SignedBinary
+
+	err = database.RetryOnBusy(ctx, func(ctx context.Context) error {
+		return database.WithinTransaction(ctx, func(ctx context.Context) error {
+			// taken before the active key is read, a rotation can't slip in between
+			if err := database.LockTable(ctx, "signed_binaries"); err != nil {
+				return err
+			}
+
+			key, err := keys.GetActive(ctx)
+			if err != nil {
+				return err
+			}
+
+			if key == nil {
+				return keys.ErrNoActiveKey
+			}
+
+			signature, err := key.Sign(sum)
+			if err != nil {
+				return err
+			}
+
+			signedBinary = NewSignedBinary(
+				WithHash(hex.EncodeToString(sum)),
+				WithSignature(signature),
+				WithKeyID(key.ID),
+				WithMetadata(metadata),
+				WithSize(counter.n),
+			)
+
+			return create(ctx, signedBinary)
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return signedBinary, nil
 }
 
-func CheckIfFileIsSigned(ctx context.Context, filePath string) (*SignedBinary, error) {
+func CheckIfFileIsSigned(ctx context.Context, filePath string) (*Verification, error) {
 	f, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
@@ -52,35 +94,101 @@ func CheckIfFileIsSigned(ctx context.Context, filePath string) (*SignedBinary, e
 	return CheckIfReaderIsSigned(ctx, f)
 }
 
-func CheckIfReaderIsSigned(ctx context.Context, reader io.Reader) (*SignedBinary, error) {
-	hash, err := digest(reader)
+// CheckIfReaderIsSigned reports whether the reader contents were signed, never signed, signed and later revoked,
+// signed by a key that was since compromised, or registered before signatures were recorded. A registered binary whose signature does not verify against its key yields ErrInvalidSignature.
+func CheckIfReaderIsSigned(ctx context.Context, reader io.Reader) (*Verification, error) {
+	sum, err := digest(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return CheckDigest(ctx, sum)
+}
+
+// CheckDigest is CheckIfReaderIsSigned for callers that already hashed the file themselves.
+func CheckDigest(ctx context.Context, sum []byte) (*Verification, error) {
+	signedBinary, err := GetSignedBinaryByHash(ctx, hex.EncodeToString(sum))
 	if err != nil {
 		return nil, err
 	}
 
-	signedBinary, err := GetSignedBinaryByHash(ctx, hash)
+	if signedBinary == nil {
+		return &Verification{Status: StatusUnsigned}, nil
+	}
+
+	// there is no signature to verify, which is not the same as one that doesn't verify
+	if signedBinary.IsLegacy() {
+		status := StatusLegacy
+		if signedBinary.IsRevoked() {
+			status = StatusRevoked
+		}
+
+		return &Verification{Status: status, SignedBinary: signedBinary}, nil
+	}
+
+	key, err := SigningKey(ctx, signedBinary)
 	if err != nil {
 		return nil, err
 	}
 
-	return signedBinary, nil
+	if err := key.Verify(sum, signedBinary.Signature); err != nil {
+		return nil, fmt.Errorf("verifying signature of %s: %w", signedBinary.Hash, ErrInvalidSignature)
+	}
+
+	if signedBinary.IsRevoked() {
+		return &Verification{Status: StatusRevoked, SignedBinary: signedBinary}, nil
+	}
+
+	// whoever holds a leaked key can produce signatures that verify just as well
+	if key.Status == keys.StatusCompromised {
+		return &Verification{Status: StatusUntrusted, SignedBinary: signedBinary}, nil
+	}
+
+	return &Verification{Status: StatusSigned, SignedBinary: signedBinary}, nil
 }
 
-func digest(reader io.Reader) (string, error) {
-	hasher := sha256.New()
+// SigningKey returns the key that produced the signature of the given binary, legacy binaries have none and yield
+// ErrUnknownKey.
+func SigningKey(ctx context.Context, sb *SignedBinary) (*keys.Key, error) {
+	if sb.KeyID == nil {
+		return nil, fmt.Errorf("binary %s has no key attributed: %w", sb.Hash, ErrUnknownKey)
+	}
 
-	if _, err := io.Copy(hasher, reader); err != nil {
-		return "", err
+	key, err := keys.GetByID(ctx, *sb.KeyID)
+	if err != nil {
+		return nil, err
 	}
 
-	buff, ok := bufferPool.Get().([]byte)
-	if !ok {
-		buff = make([]byte, 32)
+	if key == nil {
+		return nil, fmt.Errorf("binary %s references key %d: %w", sb.Hash, *sb.KeyID, ErrUnknownKey)
 	}
 
-	hashBytes := hasher.Sum(buff)
+	return key, nil
+}
 
-	hashString := hex.EncodeToString(hashBytes)
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+
+	return n, err
+}
+
+// Digest returns the raw SHA-256 of the reader contents, which is the message every signature is made over.
+func Digest(reader io.Reader) ([]byte, error) {
+	return digest(reader)
+}
+
+func digest(reader io.Reader) ([]byte, error) {
+	hasher := sha256.New()
+
+	if _, err := io.Copy(hasher, reader); err != nil {
+		return nil, err
+	}
 
-	return hashString, nil
+	return hasher.Sum(nil), nil
 }
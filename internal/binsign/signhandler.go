@@ -1,8 +1,12 @@
 package binsign
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
 
+	"github.com/Gustrb/ccanalytics/internal/events"
+	"github.com/Gustrb/ccanalytics/internal/keys"
 	"github.com/labstack/echo/v5"
 )
 
@@ -13,6 +17,21 @@ func SignHandler(c *echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "file is required")
 	}
 
+	labels, err := ParseLabels(c.Request().MultipartForm.Value["label"])
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	metadata := Metadata{
+		ArtifactName: c.FormValue("artifact_name"),
+		Version:      c.FormValue("version"),
+		OS:           c.FormValue("os"),
+		Arch:         c.FormValue("arch"),
+		Filename:     fheader.Filename,
+		Uploader:     c.FormValue("uploader"),
+		Labels:       labels,
+	}
+
 	fileHandle, err := fheader.Open()
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to open file")
@@ -21,9 +40,28 @@ func SignHandler(c *echo.Context) error {
 
 	ctx := c.Request().Context()
 
-	if err := SignFile(ctx, fileHandle); err != nil {
+	signedBinary, err := SignFile(ctx, fileHandle, metadata)
+	if err != nil {
+		if errors.Is(err, ErrDuplicateHash) {
+			return echo.NewHTTPError(http.StatusConflict, "file has already been signed")
+		}
+
+		if errors.Is(err, keys.ErrNoActiveKey) {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "no active signing key")
+		}
+
+		slog.ErrorContext(ctx, "failed to sign file", "error", err)
+
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to sign file")
+	}
+
+	events.Annotate(ctx, signedBinary.Hash, events.OutcomeSigned)
+
+	key, err := SigningKey(ctx, signedBinary)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get signing key", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to sign file")
 	}
 
-	return nil
+	return c.JSON(http.StatusCreated, NewSignedBinaryView(signedBinary, key))
 }
@@ -0,0 +1,19 @@
+package binsign
+
+type Status string
+
+const (
+	StatusSigned   Status = "signed"
+	StatusUnsigned Status = "unsigned"
+	StatusRevoked  Status = "revoked"
+	// StatusUntrusted binaries carry a valid signature made by a key that was since flagged as compromised
+	StatusUntrusted Status = "untrusted"
+	// StatusLegacy binaries were registered before signatures were recorded, only the database vouches for them
+	StatusLegacy Status = "legacy"
+)
+
+// Verification is the outcome of checking a binary, SignedBinary is nil when the binary was never signed.
+type Verification struct {
+	Status       Status
+	SignedBinary *SignedBinary
+}
@@ -0,0 +1,50 @@
+package binsign
+
+import "github.com/Gustrb/ccanalytics/internal/keys"
+
+// SignedBinaryView is the JSON representation of a signed binary handed out by the API.
+type SignedBinaryView struct {
+	Hash             string           `json:"hash"`
+	Algorithm        string           `json:"algorithm"`
+	Signature        string           `json:"signature"`
+	KeyID            string           `json:"key_id"`
+	KeyStatus        keys.Status      `json:"key_status"`
+	SignedAt         int64            `json:"signed_at"`
+	ArtifactName     string           `json:"artifact_name"`
+	Version          string           `json:"version"`
+	OS               string           `json:"os"`
+	Arch             string           `json:"arch"`
+	Filename         string           `json:"original_filename"`
+	Size             int64            `json:"size"`
+	Uploader         string           `json:"uploader"`
+	Labels           Labels           `json:"labels"`
+	RevokedAt        int64            `json:"revoked_at,omitempty"`
+	RevocationReason RevocationReason `json:"revocation_reason,omitempty"`
+}
+
+// NewSignedBinaryView renders sb, key may be nil for binaries signed before keys were tracked.
+func NewSignedBinaryView(sb *SignedBinary, key *keys.Key) SignedBinaryView {
+	view := SignedBinaryView{
+		Hash:             sb.Hash,
+		Signature:        sb.Signature,
+		SignedAt:         sb.CreatedAt,
+		ArtifactName:     sb.ArtifactName,
+		Version:          sb.Version,
+		OS:               sb.OS,
+		Arch:             sb.Arch,
+		Filename:         sb.Filename,
+		Size:             sb.Size,
+		Uploader:         sb.Uploader,
+		Labels:           sb.Labels,
+		RevokedAt:        sb.RevokedAt,
+		RevocationReason: sb.RevocationReason,
+	}
+
+	if key != nil {
+		view.Algorithm = key.Algorithm
+		view.KeyID = key.Fingerprint
+		view.KeyStatus = key.Status
+	}
+
+	return view
+}
@@ -0,0 +1,265 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Gustrb/ccanalytics/internal/binsign"
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database/migrator"
+	"github.com/Gustrb/ccanalytics/internal/keys"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	ctx := context.Background()
+
+	dir, err := os.MkdirTemp("", "ccanalytics-bundle-test")
+	if err != nil {
+		slog.Error("Failed to create temp dir", "error", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	cleanup, err := database.Connect(ctx, config.DatabaseConfig{
+		Path:        filepath.Join(dir, "app.db"),
+		JournalMode: "wal",
+		ForeignKeys: true,
+	})
+	if err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		return 1
+	}
+	defer cleanup()
+
+	if err := migrator.MigrateUp(ctx); err != nil {
+		slog.Error("Failed to migrate database", "error", err)
+		return 1
+	}
+
+	return m.Run()
+}
+
+func signRandomFile(t *testing.T) []byte {
+	t.Helper()
+
+	contents := make([]byte, 512)
+	_, err := rand.Read(contents)
+	require.NoError(t, err)
+
+	_, err = binsign.SignFile(context.Background(), bytes.NewReader(contents), binsign.Metadata{})
+	require.NoError(t, err)
+
+	return contents
+}
+
+func TestShouldCheckFilesAgainstAnExportedBundle(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := keys.Rotate(ctx)
+	require.NoError(t, err)
+
+	signed := signRandomFile(t)
+	revoked := signRandomFile(t)
+
+	sum, err := binsign.Digest(bytes.NewReader(revoked))
+	require.NoError(t, err)
+
+	_, err = binsign.RevokeFileAt(ctx, writeFile(t, revoked), binsign.ReasonSignedInError)
+	require.NoError(t, err)
+
+	envelope, err := Export(ctx)
+	require.NoError(t, err)
+
+	b, err := Open(envelope, key.PublicKey)
+	require.NoError(t, err)
+
+	result, err := b.Check(bytes.NewReader(signed))
+	require.NoError(t, err)
+	require.Equal(t, binsign.StatusSigned, result.Status)
+	require.Equal(t, key.Fingerprint, result.KeyID)
+
+	result, err = b.Check(bytes.NewReader(revoked))
+	require.NoError(t, err)
+	require.Equal(t, binsign.StatusRevoked, result.Status)
+	require.Equal(t, binsign.ReasonSignedInError, result.RevocationReason)
+	require.Equal(t, hex.EncodeToString(sum), result.Hash)
+
+	result, err = b.Check(bytes.NewReader([]byte("never signed")))
+	require.NoError(t, err)
+	require.Equal(t, binsign.StatusUnsigned, result.Status)
+}
+
+func TestShouldRefuseBundlesWithoutAPinnedKey(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := keys.Rotate(ctx)
+	require.NoError(t, err)
+
+	envelope, err := Export(ctx)
+	require.NoError(t, err)
+
+	_, err = Open(envelope, "")
+	require.ErrorIs(t, err, ErrPublicKeyRequired)
+}
+
+func TestShouldOpenBundlesAcrossAKeyRotation(t *testing.T) {
+	ctx := context.Background()
+
+	current, err := keys.Rotate(ctx)
+	require.NoError(t, err)
+
+	// the next key is pinned ahead of its activation
+	next, err := keys.Generate(ctx)
+	require.NoError(t, err)
+
+	pinned := []string{current.PublicKey, next.PublicKey}
+
+	contents := signRandomFile(t)
+
+	before, err := Export(ctx)
+	require.NoError(t, err)
+
+	_, err = keys.Activate(ctx, next.Fingerprint)
+	require.NoError(t, err)
+
+	after, err := Export(ctx)
+	require.NoError(t, err)
+	require.Equal(t, next.Fingerprint, after.KeyID)
+
+	for _, envelope := range []*keys.Envelope{before, after} {
+		b, err := Open(envelope, pinned...)
+		require.NoError(t, err)
+
+		result, err := b.Check(bytes.NewReader(contents))
+		require.NoError(t, err)
+		require.Equal(t, binsign.StatusSigned, result.Status)
+		require.Equal(t, current.Fingerprint, result.KeyID)
+	}
+
+	// a verifier that only pinned the old key can't tell the new one from an attacker's
+	_, err = Open(after, current.PublicKey)
+	require.ErrorIs(t, err, keys.ErrEnvelopeKeyMismatch)
+}
+
+func TestShouldRefuseTamperedBundles(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := keys.Rotate(ctx)
+	require.NoError(t, err)
+
+	signRandomFile(t)
+
+	envelope, err := Export(ctx)
+	require.NoError(t, err)
+
+	var b Bundle
+	require.NoError(t, json.Unmarshal(envelope.Payload, &b))
+
+	// someone in the transit path slips their own binary in
+	b.Binaries = append(b.Binaries, Binary{Hash: "deadbeef", Signature: b.Binaries[0].Signature, KeyID: key.Fingerprint})
+
+	tampered := *envelope
+	tampered.Payload, err = json.Marshal(b)
+	require.NoError(t, err)
+
+	_, err = Open(&tampered, key.PublicKey)
+	require.ErrorIs(t, err, keys.ErrInvalidSignature)
+
+	// and re-seals it with a key of their own, embedding it so the bundle is consistent with itself
+	attacker, err := keys.Generate(ctx)
+	require.NoError(t, err)
+
+	b.Keys = append(b.Keys, attacker.Public())
+
+	resealed, err := keys.SealWith(attacker, b)
+	require.NoError(t, err)
+
+	_, err = Open(resealed, key.PublicKey)
+	require.ErrorIs(t, err, keys.ErrEnvelopeKeyMismatch)
+
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	data, err := json.Marshal(resealed)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	_, err = Load(path, key.PublicKey)
+	require.ErrorIs(t, err, keys.ErrEnvelopeKeyMismatch)
+}
+
+func TestShouldNotTrustBundledSignaturesOfACompromisedKey(t *testing.T) {
+	ctx := context.Background()
+
+	compromised, err := keys.Rotate(ctx)
+	require.NoError(t, err)
+
+	signed := signRandomFile(t)
+
+	key, err := keys.Rotate(ctx)
+	require.NoError(t, err)
+
+	_, err = keys.Retire(ctx, compromised.Fingerprint, true)
+	require.NoError(t, err)
+
+	envelope, err := Export(ctx)
+	require.NoError(t, err)
+
+	b, err := Open(envelope, key.PublicKey)
+	require.NoError(t, err)
+
+	result, err := b.Check(bytes.NewReader(signed))
+	require.NoError(t, err)
+	require.Equal(t, binsign.StatusUntrusted, result.Status)
+	require.Equal(t, keys.StatusCompromised, result.KeyStatus)
+}
+
+func TestShouldAgreeWithTheAPIOnBinariesRegisteredBeforeSignatures(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := keys.Rotate(ctx)
+	require.NoError(t, err)
+
+	contents := []byte("registered before signatures were recorded")
+	sum, err := binsign.Digest(bytes.NewReader(contents))
+	require.NoError(t, err)
+
+	// what the baseline wrote, the signature and key columns were added after it
+	_, err = database.ExecContext(ctx, "insert into signed_binaries (hash, created_at, updated_at) values (?, ?, ?);", hex.EncodeToString(sum), 1, 1)
+	require.NoError(t, err)
+
+	verification, err := binsign.CheckDigest(ctx, sum)
+	require.NoError(t, err)
+	require.Equal(t, binsign.StatusLegacy, verification.Status)
+
+	envelope, err := Export(ctx)
+	require.NoError(t, err)
+
+	b, err := Open(envelope, key.PublicKey)
+	require.NoError(t, err)
+
+	result, err := b.Check(bytes.NewReader(contents))
+	require.NoError(t, err)
+	require.Equal(t, binsign.StatusLegacy, result.Status)
+	require.Equal(t, int64(1), result.SignedAt)
+}
+
+func writeFile(t *testing.T, contents []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "file.bin")
+	require.NoError(t, os.WriteFile(path, contents, 0o644))
+
+	return path
+}
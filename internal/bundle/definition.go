@@ -0,0 +1,43 @@
+package bundle
+
+import (
+	"github.com/Gustrb/ccanalytics/internal/binsign"
+	"github.com/Gustrb/ccanalytics/internal/keys"
+)
+
+const (
+	// Version is bumped whenever the bundle layout changes in a way older verifiers cannot read
+	Version = 1
+)
+
+// Bundle is everything an offline verifier needs to check binaries without reaching the API.
+type Bundle struct {
+	Version     int                       `json:"version"`
+	GeneratedAt int64                     `json:"generated_at"`
+	Binaries    []Binary                  `json:"binaries"`
+	Keys        []keys.PublicKeyInfo      `json:"keys"`
+	Revocations []binsign.RevocationEntry `json:"revocations"`
+}
+
+type Binary struct {
+	Hash      string `json:"hash"`
+	Signature string `json:"signature"`
+	KeyID     string `json:"key_id"`
+	SignedAt  int64  `json:"signed_at"`
+}
+
+// IsLegacy mirrors binsign.SignedBinary.IsLegacy, the binary was registered before signatures were recorded.
+func (b Binary) IsLegacy() bool {
+	return b.Signature == "" && b.KeyID == ""
+}
+
+// Result is the outcome of checking a file against a bundle.
+type Result struct {
+	Status           binsign.Status           `json:"status"`
+	Hash             string                   `json:"hash"`
+	KeyID            string                   `json:"key_id,omitempty"`
+	KeyStatus        keys.Status              `json:"key_status,omitempty"`
+	SignedAt         int64                    `json:"signed_at,omitempty"`
+	RevokedAt        int64                    `json:"revoked_at,omitempty"`
+	RevocationReason binsign.RevocationReason `json:"revocation_reason,omitempty"`
+}
@@ -0,0 +1,72 @@
+package bundle
+
+import (
+	"context"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/binsign"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/keys"
+)
+
+const (
+	getActiveSignedBinariesQuery = "select * from signed_binaries where revoked_at = 0 order by created_at;"
+)
+
+func Build(ctx context.Context) (*Bundle, error) {
+	signedBinaries, err := database.SelectContext[binsign.SignedBinary](ctx, getActiveSignedBinariesQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	keyList, err := keys.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	revocations, err := binsign.BuildRevocationList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprints := make(map[int]string, len(keyList))
+	b := &Bundle{
+		Version:     Version,
+		GeneratedAt: time.Now().UnixNano(),
+		Binaries:    make([]Binary, 0, len(signedBinaries)),
+		Keys:        make([]keys.PublicKeyInfo, 0, len(keyList)),
+		Revocations: revocations.Entries,
+	}
+
+	for _, k := range keyList {
+		fingerprints[k.ID] = k.Fingerprint
+		b.Keys = append(b.Keys, k.Public())
+	}
+
+	// binaries without a key are kept too, so the bundle answers for them just like the API does
+	for _, sb := range signedBinaries {
+		binary := Binary{
+			Hash:      sb.Hash,
+			Signature: sb.Signature,
+			SignedAt:  sb.CreatedAt,
+		}
+
+		if sb.KeyID != nil {
+			binary.KeyID = fingerprints[*sb.KeyID]
+		}
+
+		b.Binaries = append(b.Binaries, binary)
+	}
+
+	return b, nil
+}
+
+// Export builds the bundle and seals it as a whole with the active key, so it cannot be altered in transit.
+func Export(ctx context.Context) (*keys.Envelope, error) {
+	b, err := Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys.Seal(ctx, b)
+}
@@ -0,0 +1,23 @@
+package bundle
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+)
+
+func ExportHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	envelope, err := Export(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to export bundle", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to export bundle")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="bundle-v%d.json"`, Version))
+
+	return c.JSON(http.StatusOK, envelope)
+}
@@ -0,0 +1,9 @@
+package bundle
+
+import (
+	"github.com/labstack/echo/v5"
+)
+
+func Urls(e *echo.Echo) {
+	e.GET("/binsign/bundle", ExportHandler)
+}
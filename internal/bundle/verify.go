@@ -0,0 +1,139 @@
+package bundle
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/Gustrb/ccanalytics/internal/binsign"
+	"github.com/Gustrb/ccanalytics/internal/keys"
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported bundle version")
+	ErrPublicKeyRequired  = errors.New("at least one trusted public key is required to verify a bundle")
+)
+
+// Load reads a bundle file and verifies it as a whole against the trusted public keys.
+func Load(path string, trustedPublicKeys ...string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope keys.Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("decoding bundle: %w", err)
+	}
+
+	return Open(&envelope, trustedPublicKeys...)
+}
+
+// Open verifies the bundle against the trusted public keys, the base64 public keys the verifier got out of band.
+// Pinning the next key before it is activated keeps bundles exported after the rotation opening. The keys embedded
+// in the bundle are never used for this, anyone able to rewrite the bundle could swap them for their own.
+func Open(envelope *keys.Envelope, trustedPublicKeys ...string) (*Bundle, error) {
+	trustedPublicKeys = slices.DeleteFunc(slices.Clone(trustedPublicKeys), func(publicKey string) bool {
+		return publicKey == ""
+	})
+	if len(trustedPublicKeys) == 0 {
+		return nil, ErrPublicKeyRequired
+	}
+
+	var b Bundle
+	if err := envelope.Open(trustedPublicKeys, &b); err != nil {
+		return nil, fmt.Errorf("verifying bundle: %w", err)
+	}
+
+	if b.Version != Version {
+		return nil, fmt.Errorf("bundle version %d: %w", b.Version, ErrUnsupportedVersion)
+	}
+
+	return &b, nil
+}
+
+func (b *Bundle) CheckFileAt(filePath string) (*Result, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return b.Check(f)
+}
+
+// Check mirrors binsign.CheckIfReaderIsSigned using only what is in the bundle.
+func (b *Bundle) Check(reader io.Reader) (*Result, error) {
+	sum, err := binsign.Digest(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := hex.EncodeToString(sum)
+
+	for _, r := range b.Revocations {
+		if r.Hash == hash {
+			return &Result{
+				Status:           binsign.StatusRevoked,
+				Hash:             hash,
+				RevokedAt:        r.RevokedAt,
+				RevocationReason: r.Reason,
+			}, nil
+		}
+	}
+
+	binary := b.binary(hash)
+	if binary == nil {
+		return &Result{Status: binsign.StatusUnsigned, Hash: hash}, nil
+	}
+
+	if binary.IsLegacy() {
+		return &Result{Status: binsign.StatusLegacy, Hash: hash, SignedAt: binary.SignedAt}, nil
+	}
+
+	k := b.key(binary.KeyID)
+	if k == nil {
+		return nil, fmt.Errorf("binary %s references key %s: %w", hash, binary.KeyID, binsign.ErrUnknownKey)
+	}
+
+	if err := keys.Verify(k.PublicKey, sum, binary.Signature); err != nil {
+		return nil, fmt.Errorf("verifying signature of %s: %w", hash, binsign.ErrInvalidSignature)
+	}
+
+	status := binsign.StatusSigned
+	if k.Status == keys.StatusCompromised {
+		status = binsign.StatusUntrusted
+	}
+
+	return &Result{
+		Status:    status,
+		Hash:      hash,
+		KeyID:     k.KeyID,
+		KeyStatus: k.Status,
+		SignedAt:  binary.SignedAt,
+	}, nil
+}
+
+func (b *Bundle) binary(hash string) *Binary {
+	for i := range b.Binaries {
+		if b.Binaries[i].Hash == hash {
+			return &b.Binaries[i]
+		}
+	}
+
+	return nil
+}
+
+func (b *Bundle) key(keyID string) *keys.PublicKeyInfo {
+	for i := range b.Keys {
+		if b.Keys[i].KeyID == keyID {
+			return &b.Keys[i]
+		}
+	}
+
+	return nil
+}
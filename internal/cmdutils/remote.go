@@ -0,0 +1,53 @@
+package cmdutils
+
+import (
+	"time"
+
+	"github.com/Gustrb/ccanalytics/client"
+	"github.com/urfave/cli/v3"
+)
+
+// DefaultRemoteTimeout replaces the default of the timeout flag in remote mode, the whole file may have to be
+// uploaded and the defaults are sized for a local database.
+const DefaultRemoteTimeout = 10 * time.Minute
+
+// RemoteFlags are shared by the commands that can either open app.db or talk to a running API.
+func RemoteFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "server",
+			Usage:   "the base URL of a running ccanalytics API, when set the local database is not used",
+			Sources: cli.EnvVars("CCANALYTICS_SERVER"),
+		},
+		&cli.StringFlag{
+			Name:    "token",
+			Usage:   "the bearer token used to authenticate against the server",
+			Sources: cli.EnvVars("CCANALYTICS_TOKEN"),
+		},
+	}
+}
+
+func IsRemote(c *cli.Command) bool {
+	return c.String("server") != ""
+}
+
+// Timeout is how long the command may run, the timeout flag in seconds. In remote mode DefaultRemoteTimeout is used
+// unless the flag was given.
+func Timeout(c *cli.Command) time.Duration {
+	if IsRemote(c) && !c.IsSet("timeout") {
+		return DefaultRemoteTimeout
+	}
+
+	return time.Duration(c.Uint16("timeout")) * time.Second
+}
+
+// NewClient builds an API client out of the remote flags, name identifies the command in the user agent. The client
+// has no timeout of its own, calls are bounded by the command's context.
+func NewClient(c *cli.Command, name string) (*client.Client, error) {
+	return client.New(
+		c.String("server"),
+		client.WithToken(c.String("token")),
+		client.WithUserAgent(client.UserAgent("ccanalytics-cli/"+client.Version, name)),
+		client.WithTimeout(0),
+	)
+}
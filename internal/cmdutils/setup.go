@@ -2,20 +2,22 @@ package cmdutils
 
 import (
 	"context"
+	"errors"
 
-	_ "github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/Gustrb/ccanalytics/internal/config"
 	"github.com/Gustrb/ccanalytics/internal/infrastructure/common"
 	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database/migrator"
 )
 
 var (
-	dbFilePath = "app.db"
+	ErrNotAtLatestMigration = errors.New("database is not at the latest migration, please run the migrator command to apply all pending migrations")
 )
 
 func SetupBinary(ctx context.Context) (common.CleanupFunction, error) {
 	cleanups := []common.CleanupFunction{}
 
-	cleanup, err := database.Connect(ctx, dbFilePath)
+	cleanup, err := database.Connect(ctx, config.Database)
 	if err != nil {
 		return nil, err
 	}
@@ -24,3 +26,23 @@ func SetupBinary(ctx context.Context) (common.CleanupFunction, error) {
 
 	return common.JoinCleanup(cleanups), nil
 }
+
+// SetupMigratedBinary is SetupBinary for commands that are only opening the database lazily,
+// it refuses to hand out a connection to a database that is behind the embedded migrations.
+func SetupMigratedBinary(ctx context.Context) (common.CleanupFunction, error) {
+	cleanup, err := SetupBinary(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	areWeAtTheLatestMigration, err := migrator.AreWeAtTheLatestMigration(ctx)
+	if err != nil {
+		return nil, errors.Join(err, cleanup())
+	}
+
+	if !areWeAtTheLatestMigration {
+		return nil, errors.Join(ErrNotAtLatestMigration, cleanup())
+	}
+
+	return cleanup, nil
+}
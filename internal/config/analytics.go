@@ -0,0 +1,18 @@
+package config
+
+import "time"
+
+type AnalyticsConfig struct {
+	// RollupInterval is how often new events are folded into the rollups, zero turns the rollups off
+	RollupInterval time.Duration `envconfig:"ANALYTICS_ROLLUP_INTERVAL" default:"1m"`
+	// RollupBatchSize is how many events are folded in per transaction
+	RollupBatchSize int `envconfig:"ANALYTICS_ROLLUP_BATCH_SIZE" default:"10000"`
+}
+
+var Analytics AnalyticsConfig
+
+func init() {
+	if err := Config(&Analytics); err != nil {
+		panic(err)
+	}
+}
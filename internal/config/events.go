@@ -0,0 +1,23 @@
+package config
+
+import "time"
+
+type EventsConfig struct {
+	// BufferSize is how many events may wait to be written, events recorded while it is full are dropped. Queued
+	// events only live in memory, a crash loses them
+	BufferSize int `envconfig:"EVENTS_BUFFER_SIZE" default:"10000"`
+	// BatchSize is how many events are written per transaction
+	BatchSize int `envconfig:"EVENTS_BATCH_SIZE" default:"100"`
+	// FlushInterval bounds how long an event waits for its batch to fill up
+	FlushInterval time.Duration `envconfig:"EVENTS_FLUSH_INTERVAL" default:"1s"`
+	// ShutdownTimeout bounds how long the api waits for pending events to be written when stopping
+	ShutdownTimeout time.Duration `envconfig:"EVENTS_SHUTDOWN_TIMEOUT" default:"5s"`
+}
+
+var Events EventsConfig
+
+func init() {
+	if err := Config(&Events); err != nil {
+		panic(err)
+	}
+}
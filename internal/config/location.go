@@ -0,0 +1,20 @@
+package config
+
+type LocationConfig struct {
+	// Resolvers are tried in order until one of them knows where the request came from. Once TrustedProxies is set
+	// the CDN headers are only read from requests a trusted proxy sent, anyone else could have set them.
+	Resolvers []string `envconfig:"LOCATION_RESOLVERS" default:"cloudfront,cloudflare,fastly,mmdb"`
+	// MMDBPath is a MaxMind City or Country database, the mmdb resolver is skipped when it is empty
+	MMDBPath string `envconfig:"LOCATION_MMDB_PATH"`
+	// TrustedProxies are the addresses or CIDR ranges whose X-Forwarded-For and CDN headers are believed, e.g. the
+	// CDN edges or the load balancer behind them. When empty the CDN headers of every request are believed
+	TrustedProxies []string `envconfig:"LOCATION_TRUSTED_PROXIES"`
+}
+
+var Location LocationConfig
+
+func init() {
+	if err := Config(&Location); err != nil {
+		panic(err)
+	}
+}
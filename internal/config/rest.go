@@ -2,6 +2,8 @@ package config
 
 type RestConfig struct {
 	Addr string `envconfig:"REST_ADDR" default:":8080"`
+	// AuthToken protects the endpoints that change state, leaving it empty disables authentication
+	AuthToken string `envconfig:"REST_AUTH_TOKEN"`
 }
 
 var Rest RestConfig
@@ -0,0 +1,142 @@
+package events
+
+import (
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/rest/location"
+	"github.com/Gustrb/ccanalytics/internal/rest/useragent"
+)
+
+type Kind string
+
+const (
+	KindSign      Kind = "sign"
+	KindCheckSign Kind = "checksign"
+)
+
+type Outcome string
+
+const (
+	OutcomeSigned   Outcome = "signed"
+	OutcomeUnsigned Outcome = "unsigned"
+	OutcomeRevoked  Outcome = "revoked"
+	// OutcomeUntrusted is a binary signed by a key that was since flagged as compromised
+	OutcomeUntrusted Outcome = "untrusted"
+	// OutcomeLegacy is a binary registered before signatures were recorded
+	OutcomeLegacy Outcome = "legacy"
+	// OutcomeInvalid is a registered binary whose signature doesn't verify
+	OutcomeInvalid Outcome = "invalid"
+	// OutcomeRejected is a request refused before reaching an outcome, e.g. a duplicate sign or a missing file
+	OutcomeRejected Outcome = "rejected"
+	OutcomeError    Outcome = "error"
+)
+
+// Event is one call to an endpoint we keep analytics for, events are never updated once written.
+type Event struct {
+	ID          int     `sql:"id" json:"-"`
+	Kind        Kind    `sql:"kind" json:"kind"`
+	Hash        string  `sql:"hash" json:"hash"`
+	Outcome     Outcome `sql:"outcome" json:"outcome"`
+	StatusCode  int     `sql:"status_code" json:"status_code"`
+	RequestID   string  `sql:"request_id" json:"request_id"`
+	City        string  `sql:"city" json:"city"`
+	CountryCode string  `sql:"country_code" json:"country_code"`
+	CountryName string  `sql:"country_name" json:"country_name"`
+	TimeZone    string  `sql:"time_zone" json:"time_zone"`
+	IsDesktop   *bool   `sql:"is_desktop" json:"is_desktop"`
+	IsMobile    *bool   `sql:"is_mobile" json:"is_mobile"`
+	IsTablet    *bool   `sql:"is_tablet" json:"is_tablet"`
+	UserAgent   string  `sql:"user_agent" json:"user_agent"`
+	// the client fields are what the user agent tells, see useragent.Client
+	ClientKind    useragent.Kind `sql:"client_kind" json:"client_kind"`
+	ClientName    string         `sql:"client_name" json:"client_name"`
+	ClientVersion string         `sql:"client_version" json:"client_version"`
+	ClientOS      string         `sql:"client_os" json:"client_os"`
+	ClientDevice  string         `sql:"client_device" json:"client_device"`
+	ClientCommand string         `sql:"client_command" json:"client_command"`
+	ClientCI      string         `sql:"client_ci" json:"client_ci"`
+	// LatencyMicros is how long the handler took, in microseconds
+	LatencyMicros int64 `sql:"latency_us" json:"latency_us"`
+	CreatedAt     int64 `sql:"created_at" json:"created_at"`
+}
+
+var eventRepository = database.NewRepository[Event]("events")
+
+func (e *Event) GetID() int {
+	return e.ID
+}
+
+func (e *Event) SetID(id int) {
+	e.ID = id
+}
+
+func (e *Event) GetCreatedAt() int64 {
+	return e.CreatedAt
+}
+
+type EventOptions func(*Event)
+
+func WithKind(kind Kind) EventOptions {
+	return func(e *Event) {
+		e.Kind = kind
+	}
+}
+
+func WithRequestID(requestID string) EventOptions {
+	return func(e *Event) {
+		e.RequestID = requestID
+	}
+}
+
+// WithLocation copies the viewer location, a nil location leaves the fields empty.
+func WithLocation(loc *location.Location) EventOptions {
+	return func(e *Event) {
+		if loc == nil {
+			return
+		}
+
+		e.City = loc.City
+		e.CountryCode = loc.CountryCode
+		e.CountryName = loc.CountryName
+		e.TimeZone = loc.TimeZone
+		e.IsDesktop = loc.IsDesktop
+		e.IsMobile = loc.IsMobile
+		e.IsTablet = loc.IsTablet
+	}
+}
+
+func WithUserAgent(userAgent string) EventOptions {
+	return func(e *Event) {
+		e.UserAgent = userAgent
+	}
+}
+
+// WithClient copies what the user agent tells about the caller, a nil client leaves the fields empty.
+func WithClient(client *useragent.Client) EventOptions {
+	return func(e *Event) {
+		if client == nil {
+			return
+		}
+
+		e.ClientKind = client.Kind
+		e.ClientName = client.Name
+		e.ClientVersion = client.Version
+		e.ClientOS = client.OS
+		e.ClientDevice = client.Device
+		e.ClientCommand = client.Command
+		e.ClientCI = client.CI
+	}
+}
+
+func NewEvent(opts ...EventOptions) *Event {
+	e := &Event{}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	e.CreatedAt = time.Now().UnixNano()
+
+	return e
+}
@@ -0,0 +1,370 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database/migrator"
+	"github.com/Gustrb/ccanalytics/internal/rest"
+	"github.com/Gustrb/ccanalytics/internal/rest/location"
+	"github.com/Gustrb/ccanalytics/internal/rest/useragent"
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	ctx := context.Background()
+
+	dir, err := os.MkdirTemp("", "ccanalytics-events-test")
+	if err != nil {
+		slog.Error("Failed to create temp dir", "error", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	cleanup, err := database.Connect(ctx, config.DatabaseConfig{
+		Path:        filepath.Join(dir, "app.db"),
+		JournalMode: "wal",
+		ForeignKeys: true,
+	})
+	if err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		return 1
+	}
+	defer cleanup()
+
+	if err := migrator.MigrateUp(ctx); err != nil {
+		slog.Error("Failed to migrate database", "error", err)
+		return 1
+	}
+
+	return m.Run()
+}
+
+func startTestWriter(t *testing.T) *Writer {
+	t.Helper()
+
+	w := Start(config.EventsConfig{BufferSize: 100, BatchSize: 2, FlushInterval: time.Hour})
+	t.Cleanup(func() {
+		require.NoError(t, w.Close(context.Background()))
+	})
+
+	return w
+}
+
+func TestShouldWriteQueuedEventsOnClose(t *testing.T) {
+	ctx := context.Background()
+	w := startTestWriter(t)
+
+	for range 5 {
+		Record(NewEvent(WithKind(KindCheckSign), WithRequestID("close-test")))
+	}
+
+	require.NoError(t, w.Close(ctx))
+
+	// closed writers drop what they are given
+	Record(NewEvent(WithKind(KindCheckSign), WithRequestID("close-test")))
+	w.Record(NewEvent(WithKind(KindCheckSign), WithRequestID("close-test")))
+
+	page, err := List(ctx, ListFilter{RequestID: "close-test"})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 5)
+}
+
+func TestShouldDrainTheQueueOnShutdown(t *testing.T) {
+	ctx := context.Background()
+	w := Start(config.EventsConfig{BufferSize: 100, BatchSize: 100, FlushInterval: time.Hour})
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	held := make(chan error, 1)
+
+	go func() {
+		held <- database.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := database.LockTable(ctx, "events"); err != nil {
+				return err
+			}
+
+			close(locked)
+			<-release
+
+			return nil
+		})
+	}()
+
+	<-locked
+
+	for range 5 {
+		w.Record(NewEvent(WithKind(KindCheckSign), WithRequestID("drain-test")))
+	}
+
+	// the events can't be written while the table is locked, a shutdown that doesn't wait leaves them behind
+	hurried, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, w.Close(hurried), context.DeadlineExceeded)
+
+	close(release)
+	require.NoError(t, <-held)
+
+	// given the time, every queued event is written
+	require.NoError(t, w.Close(ctx))
+	require.Equal(t, Stats{}, w.Stats())
+
+	page, err := List(ctx, ListFilter{RequestID: "drain-test"})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 5)
+}
+
+func TestShouldCountTheEventsItDrops(t *testing.T) {
+	// never started, nothing takes events off the queue
+	w := &Writer{queue: make(chan *Event, 1), done: make(chan struct{})}
+
+	for range 3 {
+		w.Record(NewEvent(WithKind(KindCheckSign)))
+	}
+
+	require.Equal(t, Stats{Queued: 1, Dropped: 2}, w.Stats())
+}
+
+func TestShouldCapTheListLimitAtTheMaximum(t *testing.T) {
+	ctx := context.Background()
+
+	// large enough a queue that none of them is dropped
+	w := Start(config.EventsConfig{BufferSize: MaxListLimit + 1, BatchSize: 500, FlushInterval: time.Hour})
+
+	for range MaxListLimit + 1 {
+		Record(NewEvent(WithKind(KindCheckSign), WithRequestID("list-limit")))
+	}
+
+	require.NoError(t, w.Close(ctx))
+
+	page, err := List(ctx, ListFilter{RequestID: "list-limit", Limit: MaxListLimit + 1})
+	require.NoError(t, err)
+	require.Len(t, page.Items, MaxListLimit)
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = List(ctx, ListFilter{RequestID: "list-limit"})
+	require.NoError(t, err)
+	require.Len(t, page.Items, DefaultListLimit)
+}
+
+func TestShouldTrackTheOutcomeOfEveryCall(t *testing.T) {
+	ctx := context.Background()
+	w := startTestWriter(t)
+
+	locations, err := location.NewChain(config.LocationConfig{Resolvers: []string{location.ResolverCloudFront}, TrustedProxies: []string{"192.0.2.1"}})
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(rest.WithRequestID)
+	e.Use(rest.WithLocation(locations))
+	e.Use(rest.WithUserAgent)
+	e.Use(rest.WithLogging)
+
+	e.POST("/unsigned", func(c *echo.Context) error {
+		Annotate(c.Request().Context(), "abc", OutcomeUnsigned)
+		return echo.NewHTTPError(http.StatusNotFound, "file is not signed")
+	}, Track(KindCheckSign))
+	e.POST("/broken", func(c *echo.Context) error {
+		Annotate(c.Request().Context(), "def", OutcomeSigned)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get signing key")
+	}, Track(KindCheckSign))
+	e.POST("/refused", func(c *echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadRequest, "file is required")
+	}, Track(KindSign))
+
+	call := func(path, requestID string) {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("X-Request-ID", requestID)
+		req.Header.Set("User-Agent", "ccanalytics-go/0.1.0 (linux/amd64; ci=github-actions)")
+		req.Header.Set("Cloudfront-Viewer-Country", "BR")
+		req.Header.Set("Cloudfront-Is-Mobile-Viewer", "true")
+
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	call("/unsigned", "track-unsigned")
+	call("/broken", "track-broken")
+	call("/refused", "track-refused")
+
+	require.NoError(t, w.Close(ctx))
+
+	expected := map[string]struct {
+		kind       Kind
+		hash       string
+		outcome    Outcome
+		statusCode int
+	}{
+		"track-unsigned": {KindCheckSign, "abc", OutcomeUnsigned, http.StatusNotFound},
+		"track-broken":   {KindCheckSign, "def", OutcomeError, http.StatusInternalServerError},
+		"track-refused":  {KindSign, "", OutcomeRejected, http.StatusBadRequest},
+	}
+
+	for requestID, want := range expected {
+		page, err := List(ctx, ListFilter{RequestID: requestID})
+		require.NoError(t, err)
+		require.Len(t, page.Items, 1, requestID)
+
+		event := page.Items[0]
+		require.Equal(t, want.kind, event.Kind, requestID)
+		require.Equal(t, want.hash, event.Hash, requestID)
+		require.Equal(t, want.outcome, event.Outcome, requestID)
+		require.Equal(t, want.statusCode, event.StatusCode, requestID)
+		require.Equal(t, "BR", event.CountryCode, requestID)
+		require.NotNil(t, event.IsMobile, requestID)
+		require.True(t, *event.IsMobile, requestID)
+		require.Nil(t, event.IsDesktop, requestID)
+		require.Equal(t, "ccanalytics-go/0.1.0 (linux/amd64; ci=github-actions)", event.UserAgent, requestID)
+		require.Equal(t, useragent.KindSDK, event.ClientKind, requestID)
+		require.Equal(t, "0.1.0", event.ClientVersion, requestID)
+		require.Equal(t, "Linux", event.ClientOS, requestID)
+		require.Equal(t, "github-actions", event.ClientCI, requestID)
+	}
+}
+
+func TestShouldTrackEveryItemOfABatch(t *testing.T) {
+	ctx := context.Background()
+	w := startTestWriter(t)
+
+	e := echo.New()
+	e.Use(rest.WithRequestID)
+	e.Use(rest.WithUserAgent)
+
+	e.POST("/check", func(c *echo.Context) error {
+		AnnotateItem(c.Request().Context(), "batch-unknown", OutcomeUnsigned)
+		AnnotateItem(c.Request().Context(), "batch-signed", OutcomeSigned)
+		AnnotateItem(c.Request().Context(), "", OutcomeRejected)
+		return c.NoContent(http.StatusOK)
+	}, Track(KindCheckSign))
+
+	req := httptest.NewRequest(http.MethodPost, "/check", nil)
+	req.Header.Set("X-Request-ID", "track-batch")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.NoError(t, w.Close(ctx))
+
+	page, err := List(ctx, ListFilter{RequestID: "track-batch"})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 3)
+
+	outcomes := map[string]Outcome{}
+	for _, event := range page.Items {
+		require.Equal(t, KindCheckSign, event.Kind)
+		require.Equal(t, http.StatusOK, event.StatusCode)
+		outcomes[event.Hash] = event.Outcome
+	}
+	require.Equal(t, map[string]Outcome{"batch-unknown": OutcomeUnsigned, "batch-signed": OutcomeSigned, "": OutcomeRejected}, outcomes)
+
+	reports, err := ListUnknown(ctx, UnknownFilter{})
+	require.NoError(t, err)
+
+	var hashes []string
+	for _, report := range reports {
+		hashes = append(hashes, report.Hash)
+	}
+	require.Contains(t, hashes, "batch-unknown")
+	require.NotContains(t, hashes, "batch-signed")
+}
+
+func TestShouldTrackUnknownHashes(t *testing.T) {
+	ctx := context.Background()
+	w := startTestWriter(t)
+
+	unsigned := func(hash, countryCode string, at int64) *Event {
+		e := NewEvent(WithKind(KindCheckSign), WithLocation(&location.Location{CountryCode: countryCode}))
+		e.Hash = hash
+		e.Outcome = OutcomeUnsigned
+		e.CreatedAt = at
+
+		return e
+	}
+
+	// batches of two, so the counts of one hash are added to across transactions, and out of order
+	w.Record(unsigned("unknown-a", "AQ", 300))
+	w.Record(unsigned("unknown-a", "AQ", 100))
+	w.Record(unsigned("unknown-a", "GS", 500))
+	w.Record(unsigned("unknown-b", "GS", 200))
+	w.Record(unsigned("unknown-a", "AQ", 50))
+
+	// neither of these is an unknown hash
+	signed := unsigned("unknown-c", "AQ", 100)
+	signed.Outcome = OutcomeSigned
+	w.Record(signed)
+	w.Record(unsigned("", "AQ", 100))
+
+	require.NoError(t, w.Close(ctx))
+
+	reports, err := ListUnknown(ctx, UnknownFilter{CountryCode: "aq"})
+	require.NoError(t, err)
+	require.Empty(t, reports, "country codes are matched as given")
+
+	reports, err = ListUnknown(ctx, UnknownFilter{CountryCode: "AQ"})
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+
+	a := reports[0]
+	require.Equal(t, "unknown-a", a.Hash)
+	require.Equal(t, int64(4), a.Total)
+	require.Equal(t, int64(50), a.FirstSeenAt)
+	require.Equal(t, int64(500), a.LastSeenAt)
+	require.Len(t, a.Countries, 2)
+	require.Equal(t, "AQ", a.Countries[0].CountryCode)
+	require.Equal(t, int64(3), a.Countries[0].Total)
+	require.Equal(t, int64(50), a.Countries[0].FirstSeenAt)
+	require.Equal(t, int64(300), a.Countries[0].LastSeenAt)
+	require.Equal(t, "GS", a.Countries[1].CountryCode)
+
+	reports, err = ListUnknown(ctx, UnknownFilter{CountryCode: "GS", Sort: UnknownSortFirstSeen})
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	require.Equal(t, "unknown-b", reports[0].Hash)
+	require.Equal(t, "unknown-a", reports[1].Hash)
+
+	reports, err = ListUnknown(ctx, UnknownFilter{CountryCode: "GS", SeenAfter: 400})
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	require.Equal(t, "unknown-a", reports[0].Hash)
+
+	_, err = ListUnknown(ctx, UnknownFilter{Sort: "newest"})
+	require.ErrorIs(t, err, ErrUnknownSort)
+}
+
+func TestShouldCapTheUnknownLimitAtTheMaximum(t *testing.T) {
+	ctx := context.Background()
+
+	// large enough a queue that none of them is dropped
+	w := Start(config.EventsConfig{BufferSize: MaxUnknownLimit + 1, BatchSize: 50, FlushInterval: time.Hour})
+
+	// a country of its own, away from the hashes of the other tests
+	for i := range MaxUnknownLimit + 1 {
+		e := NewEvent(WithKind(KindCheckSign), WithLocation(&location.Location{CountryCode: "TF"}))
+		e.Hash = fmt.Sprintf("unknown-limit-%03d", i)
+		e.Outcome = OutcomeUnsigned
+
+		w.Record(e)
+	}
+
+	require.NoError(t, w.Close(ctx))
+
+	reports, err := ListUnknown(ctx, UnknownFilter{CountryCode: "TF", Limit: MaxUnknownLimit + 1})
+	require.NoError(t, err)
+	require.Len(t, reports, MaxUnknownLimit)
+
+	reports, err = ListUnknown(ctx, UnknownFilter{CountryCode: "TF"})
+	require.NoError(t, err)
+	require.Len(t, reports, DefaultUnknownLimit)
+}
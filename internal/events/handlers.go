@@ -0,0 +1,12 @@
+package events
+
+import (
+	"github.com/Gustrb/ccanalytics/internal/rest"
+	"github.com/labstack/echo/v5"
+)
+
+func Urls(e *echo.Echo) {
+	// events carry the location and user agent of whoever called us, they are not for everyone to read
+	e.GET("/events", ListHandler, rest.RequireToken)
+	e.GET("/events/unknown-hashes", ListUnknownHandler, rest.RequireToken)
+}
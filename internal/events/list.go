@@ -0,0 +1,78 @@
+package events
+
+import (
+	"context"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/rest/useragent"
+)
+
+const (
+	listEventsQuery = "select * from events"
+
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+// ListFilter narrows down events, empty fields are ignored.
+type ListFilter struct {
+	Kind        Kind
+	Outcome     Outcome
+	Hash        string
+	RequestID   string
+	CountryCode string
+	ClientKind  useragent.Kind
+
+	CreatedAfter  int64
+	CreatedBefore int64
+
+	Cursor string
+	Limit  int
+	Order  database.SortOrder
+}
+
+func (f ListFilter) where() ([]string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+
+	equals := func(column, value string) {
+		if value != "" {
+			conditions = append(conditions, column+" = ?")
+			args = append(args, value)
+		}
+	}
+
+	equals("kind", string(f.Kind))
+	equals("outcome", string(f.Outcome))
+	equals("hash", f.Hash)
+	equals("request_id", f.RequestID)
+	equals("country_code", f.CountryCode)
+	equals("client_kind", string(f.ClientKind))
+
+	if f.CreatedAfter != 0 {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, f.CreatedAfter)
+	}
+
+	if f.CreatedBefore != 0 {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, f.CreatedBefore)
+	}
+
+	return conditions, args
+}
+
+func List(ctx context.Context, filter ListFilter) (*database.Page[Event], error) {
+	conditions, args := filter.where()
+
+	return database.SelectPageContext[Event](ctx, database.PageQuery{
+		Query:  listEventsQuery,
+		Where:  conditions,
+		Args:   args,
+		Cursor: filter.Cursor,
+		Limit:  database.ClampLimit(filter.Limit, DefaultListLimit, MaxListLimit),
+		Order:  filter.Order,
+	})
+}
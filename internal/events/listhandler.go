@@ -0,0 +1,70 @@
+package events
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/rest"
+	"github.com/Gustrb/ccanalytics/internal/rest/useragent"
+	"github.com/labstack/echo/v5"
+)
+
+func ListHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	limit, err := echo.QueryParamOr(c, "limit", DefaultListLimit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "limit must be an integer")
+	}
+
+	order, err := database.ParseSortOrder(c.QueryParam("order"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	createdAfter, err := rest.ParseTimeParam(c.QueryParam("created_after"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "created_after must be an RFC 3339 date or a unix timestamp in nanoseconds")
+	}
+
+	createdBefore, err := rest.ParseTimeParam(c.QueryParam("created_before"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "created_before must be an RFC 3339 date or a unix timestamp in nanoseconds")
+	}
+
+	page, err := List(ctx, ListFilter{
+		Kind:          Kind(c.QueryParam("kind")),
+		Outcome:       Outcome(c.QueryParam("outcome")),
+		Hash:          strings.ToLower(c.QueryParam("hash")),
+		RequestID:     c.QueryParam("request_id"),
+		CountryCode:   strings.ToUpper(c.QueryParam("country_code")),
+		ClientKind:    useragent.Kind(c.QueryParam("client_kind")),
+		CreatedAfter:  createdAfter,
+		CreatedBefore: createdBefore,
+		Cursor:        c.QueryParam("cursor"),
+		Limit:         limit,
+		Order:         order,
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidCursor) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		slog.ErrorContext(ctx, "failed to list events", "error", err)
+
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list events")
+	}
+
+	items := page.Items
+	if items == nil {
+		items = []*Event{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"events":      items,
+		"next_cursor": page.NextCursor,
+	})
+}
@@ -0,0 +1,131 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/rest/contextkey"
+	"github.com/Gustrb/ccanalytics/internal/rest/location"
+	"github.com/Gustrb/ccanalytics/internal/rest/useragent"
+	"github.com/labstack/echo/v5"
+)
+
+type eventKey struct{}
+
+// tracked is what Track keeps in the request context for handlers to annotate.
+type tracked struct {
+	event *Event
+	// items are set instead of the event's hash and outcome by handlers of batch requests, see AnnotateItem
+	items []item
+}
+
+type item struct {
+	hash    string
+	outcome Outcome
+}
+
+// Track records every call of the route as an event of the given kind once the handler returns. It must run
+// after WithRequestID, WithLocation and WithUserAgent, handlers fill in the hash and outcome through Annotate,
+// or AnnotateItem for requests carrying a batch.
+func Track(kind Kind) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			startTime := time.Now()
+
+			ctx := c.Request().Context()
+
+			requestID, _ := ctx.Value(contextkey.RequestIDKey).(string)
+			loc, _ := ctx.Value(contextkey.LocationKey).(*location.Location)
+			client, _ := ctx.Value(contextkey.ClientKey).(*useragent.Client)
+
+			t := &tracked{
+				event: NewEvent(
+					WithKind(kind),
+					WithRequestID(requestID),
+					WithLocation(loc),
+					WithUserAgent(c.Request().UserAgent()),
+					WithClient(client),
+				),
+			}
+
+			c.SetRequest(c.Request().WithContext(context.WithValue(ctx, eventKey{}, t)))
+
+			err := next(c)
+
+			t.event.LatencyMicros = time.Since(startTime).Microseconds()
+			t.event.StatusCode = statusCode(c, err)
+
+			for _, event := range t.events() {
+				// a server error wins over whatever outcome the handler reached, the caller never got to see it
+				switch {
+				case event.StatusCode >= http.StatusInternalServerError:
+					event.Outcome = OutcomeError
+				case event.Outcome == "":
+					event.Outcome = OutcomeRejected
+				}
+
+				Record(event)
+			}
+
+			return err
+		}
+	}
+}
+
+// events are what the request is recorded as, one event per item of a batch or the request's own event otherwise.
+func (t *tracked) events() []*Event {
+	if len(t.items) == 0 {
+		return []*Event{t.event}
+	}
+
+	events := make([]*Event, 0, len(t.items))
+	for _, it := range t.items {
+		event := *t.event
+		event.Hash = it.hash
+		event.Outcome = it.outcome
+
+		events = append(events, &event)
+	}
+
+	return events
+}
+
+// Annotate sets what only the handler knows about the event of the request, it is a no-op on untracked routes.
+func Annotate(ctx context.Context, hash string, outcome Outcome) {
+	t, ok := ctx.Value(eventKey{}).(*tracked)
+	if !ok {
+		return
+	}
+
+	t.event.Hash = hash
+	t.event.Outcome = outcome
+}
+
+// AnnotateItem adds an item of a batch request, which is then recorded as one event per item sharing everything
+// but the hash and outcome. It is a no-op on untracked routes.
+func AnnotateItem(ctx context.Context, hash string, outcome Outcome) {
+	t, ok := ctx.Value(eventKey{}).(*tracked)
+	if !ok {
+		return
+	}
+
+	t.items = append(t.items, item{hash: hash, outcome: outcome})
+}
+
+func statusCode(c *echo.Context, err error) int {
+	if err == nil {
+		if res, uerr := echo.UnwrapResponse(c.Response()); uerr == nil && res.Status != 0 {
+			return res.Status
+		}
+
+		return http.StatusOK
+	}
+
+	if httpErr, ok := errors.AsType[*echo.HTTPError](err); ok {
+		return httpErr.Code
+	}
+
+	return http.StatusInternalServerError
+}
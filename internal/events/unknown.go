@@ -0,0 +1,232 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
+
+// UnknownHash is a hash someone checked that we never signed, seen often it is likely a tampered or unofficial
+// build in the wild.
+type UnknownHash struct {
+	ID          int    `sql:"id" json:"-"`
+	Hash        string `sql:"hash" json:"hash"`
+	Total       int64  `sql:"total" json:"total"`
+	FirstSeenAt int64  `sql:"first_seen_at" json:"first_seen_at"`
+	LastSeenAt  int64  `sql:"last_seen_at" json:"last_seen_at"`
+	CreatedAt   int64  `sql:"created_at" json:"-"`
+	UpdatedAt   int64  `sql:"updated_at" json:"-"`
+}
+
+// UnknownHashCountry is how often an unknown hash was checked from one country, the country is empty for checks
+// that didn't come through the CDN.
+type UnknownHashCountry struct {
+	ID          int    `sql:"id" json:"-"`
+	Hash        string `sql:"hash" json:"-"`
+	CountryCode string `sql:"country_code" json:"country_code"`
+	Total       int64  `sql:"total" json:"total"`
+	FirstSeenAt int64  `sql:"first_seen_at" json:"first_seen_at"`
+	LastSeenAt  int64  `sql:"last_seen_at" json:"last_seen_at"`
+	CreatedAt   int64  `sql:"created_at" json:"-"`
+	UpdatedAt   int64  `sql:"updated_at" json:"-"`
+}
+
+type UnknownSort string
+
+const (
+	UnknownSortTotal     UnknownSort = "total"
+	UnknownSortLastSeen  UnknownSort = "last_seen"
+	UnknownSortFirstSeen UnknownSort = "first_seen"
+
+	DefaultUnknownLimit = 20
+	MaxUnknownLimit     = 100
+)
+
+var ErrUnknownSort = errors.New("sort must be one of total, last_seen or first_seen")
+
+var unknownOrderBy = map[UnknownSort]string{
+	UnknownSortTotal:     "total desc, hash",
+	UnknownSortLastSeen:  "last_seen_at desc, hash",
+	UnknownSortFirstSeen: "first_seen_at desc, hash",
+}
+
+const (
+	// counts are added to and the seen range only ever widens, batches may arrive in any order
+	addUnknownHashQuery = `insert into unknown_hashes (hash, total, first_seen_at, last_seen_at, created_at, updated_at)
+	values (?, ?, ?, ?, ?, ?)
+	on conflict (hash) do update set
+	total = unknown_hashes.total + excluded.total,
+	first_seen_at = case when excluded.first_seen_at < unknown_hashes.first_seen_at then excluded.first_seen_at else unknown_hashes.first_seen_at end,
+	last_seen_at = case when excluded.last_seen_at > unknown_hashes.last_seen_at then excluded.last_seen_at else unknown_hashes.last_seen_at end,
+	updated_at = excluded.updated_at;`
+
+	addUnknownHashCountryQuery = `insert into unknown_hash_countries (hash, country_code, total, first_seen_at, last_seen_at, created_at, updated_at)
+	values (?, ?, ?, ?, ?, ?, ?)
+	on conflict (hash, country_code) do update set
+	total = unknown_hash_countries.total + excluded.total,
+	first_seen_at = case when excluded.first_seen_at < unknown_hash_countries.first_seen_at then excluded.first_seen_at else unknown_hash_countries.first_seen_at end,
+	last_seen_at = case when excluded.last_seen_at > unknown_hash_countries.last_seen_at then excluded.last_seen_at else unknown_hash_countries.last_seen_at end,
+	updated_at = excluded.updated_at;`
+
+	listUnknownHashesQuery = "select * from unknown_hashes"
+
+	listUnknownHashCountriesQuery = "select * from unknown_hash_countries where hash in (%s) order by total desc, country_code;"
+)
+
+// sighting is what a batch adds to one unknown hash, or to one of its countries.
+type sighting struct {
+	total       int64
+	firstSeenAt int64
+	lastSeenAt  int64
+}
+
+func (s *sighting) add(createdAt int64) {
+	if s.total == 0 || createdAt < s.firstSeenAt {
+		s.firstSeenAt = createdAt
+	}
+
+	s.lastSeenAt = max(s.lastSeenAt, createdAt)
+	s.total++
+}
+
+type hashCountry struct {
+	hash        string
+	countryCode string
+}
+
+// recordUnknown folds the unsigned checks of the batch into the unknown hashes, within the transaction that
+// writes the batch so the two never disagree.
+func recordUnknown(ctx context.Context, batch []*Event, now int64) error {
+	hashes := map[string]*sighting{}
+	countries := map[hashCountry]*sighting{}
+
+	for _, e := range batch {
+		if e.Kind != KindCheckSign || e.Outcome != OutcomeUnsigned || e.Hash == "" {
+			continue
+		}
+
+		if hashes[e.Hash] == nil {
+			hashes[e.Hash] = &sighting{}
+		}
+		hashes[e.Hash].add(e.CreatedAt)
+
+		key := hashCountry{e.Hash, e.CountryCode}
+		if countries[key] == nil {
+			countries[key] = &sighting{}
+		}
+		countries[key].add(e.CreatedAt)
+	}
+
+	// rows are written in the same order by every writer, so two of them can't deadlock on each other
+	for _, hash := range slices.Sorted(maps.Keys(hashes)) {
+		s := hashes[hash]
+		if _, err := database.ExecContext(ctx, addUnknownHashQuery, hash, s.total, s.firstSeenAt, s.lastSeenAt, now, now); err != nil {
+			return err
+		}
+	}
+
+	keys := slices.SortedFunc(maps.Keys(countries), func(a, b hashCountry) int {
+		return strings.Compare(a.hash+"\x00"+a.countryCode, b.hash+"\x00"+b.countryCode)
+	})
+
+	for _, key := range keys {
+		s := countries[key]
+		if _, err := database.ExecContext(ctx, addUnknownHashCountryQuery, key.hash, key.countryCode, s.total, s.firstSeenAt, s.lastSeenAt, now, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UnknownFilter narrows down unknown hashes, empty fields are ignored.
+type UnknownFilter struct {
+	// SeenAfter keeps the hashes checked at or after it
+	SeenAfter int64
+	// CountryCode keeps the hashes checked at least once from the country
+	CountryCode string
+
+	Sort  UnknownSort
+	Limit int
+}
+
+// UnknownHashReport is an unknown hash along with every country it was checked from, most checks first.
+type UnknownHashReport struct {
+	*UnknownHash
+	Countries []*UnknownHashCountry `json:"countries"`
+}
+
+// ListUnknown returns the unknown hashes ordered by filter.Sort, most checked first by default.
+func ListUnknown(ctx context.Context, filter UnknownFilter) ([]*UnknownHashReport, error) {
+	sort := filter.Sort
+	if sort == "" {
+		sort = UnknownSortTotal
+	}
+
+	orderBy, ok := unknownOrderBy[sort]
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", sort, ErrUnknownSort)
+	}
+
+	limit := database.ClampLimit(filter.Limit, DefaultUnknownLimit, MaxUnknownLimit)
+
+	var (
+		conditions []string
+		args       []any
+	)
+
+	if filter.SeenAfter != 0 {
+		conditions = append(conditions, "last_seen_at >= ?")
+		args = append(args, filter.SeenAfter)
+	}
+
+	if filter.CountryCode != "" {
+		conditions = append(conditions, "hash in (select hash from unknown_hash_countries where country_code = ?)")
+		args = append(args, filter.CountryCode)
+	}
+
+	query := listUnknownHashesQuery
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+	query += " order by " + orderBy + " limit ?;"
+
+	hashes, err := database.SelectContext[UnknownHash](ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*UnknownHashReport, 0, len(hashes))
+	if len(hashes) == 0 {
+		return reports, nil
+	}
+
+	byHash := make(map[string]*UnknownHashReport, len(hashes))
+	hashArgs := make([]any, 0, len(hashes))
+
+	for _, h := range hashes {
+		report := &UnknownHashReport{UnknownHash: h, Countries: []*UnknownHashCountry{}}
+
+		reports = append(reports, report)
+		byHash[h.Hash] = report
+		hashArgs = append(hashArgs, h.Hash)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(hashArgs)), ", ")
+
+	countries, err := database.SelectContext[UnknownHashCountry](ctx, fmt.Sprintf(listUnknownHashCountriesQuery, placeholders), hashArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range countries {
+		byHash[c.Hash].Countries = append(byHash[c.Hash].Countries, c)
+	}
+
+	return reports, nil
+}
@@ -0,0 +1,45 @@
+package events
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Gustrb/ccanalytics/internal/rest"
+	"github.com/labstack/echo/v5"
+)
+
+func ListUnknownHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	limit, err := echo.QueryParamOr(c, "limit", DefaultUnknownLimit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "limit must be an integer")
+	}
+
+	seenAfter, err := rest.ParseTimeParam(c.QueryParam("seen_after"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "seen_after must be an RFC 3339 date or a unix timestamp in nanoseconds")
+	}
+
+	reports, err := ListUnknown(ctx, UnknownFilter{
+		SeenAfter:   seenAfter,
+		CountryCode: strings.ToUpper(c.QueryParam("country_code")),
+		Sort:        UnknownSort(c.QueryParam("sort")),
+		Limit:       limit,
+	})
+	if err != nil {
+		if errors.Is(err, ErrUnknownSort) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		slog.ErrorContext(ctx, "failed to list unknown hashes", "error", err)
+
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list unknown hashes")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"unknown_hashes": reports,
+	})
+}
@@ -0,0 +1,183 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
+
+// Writer stores events in batches from a goroutine of its own, so recording one never waits on the database.
+// It has its own context, events outlive the request that produced them and never take part in its transaction.
+//
+// Events are only kept in memory until their batch is written, which takes up to the flush interval. The ones
+// still queued when the process dies, the ones recorded while the queue is full and the ones of a batch that
+// can't be written are lost, Stats counts the last two. Close writes what is queued, so a clean shutdown loses
+// nothing unless it times out.
+type Writer struct {
+	queue         chan *Event
+	batchSize     int
+	flushInterval time.Duration
+
+	// mu keeps Record from sending on queue once Close has closed it
+	mu     sync.RWMutex
+	closed bool
+
+	done    chan struct{}
+	dropped atomic.Int64
+	failed  atomic.Int64
+}
+
+// Stats tells how many events wait to be written and how many were lost since the writer started.
+type Stats struct {
+	Queued int `json:"queued"`
+	// Dropped events were recorded while the queue was full
+	Dropped int64 `json:"dropped"`
+	// Failed events were part of a batch that could not be written
+	Failed int64 `json:"failed"`
+}
+
+// defaultWriter is the writer Record sends to, it is only set while the api runs.
+var defaultWriter atomic.Pointer[Writer]
+
+// Start runs a writer until Close is called and makes it the one Record sends to.
+func Start(cfg config.EventsConfig) *Writer {
+	w := &Writer{
+		queue:         make(chan *Event, max(cfg.BufferSize, 1)),
+		batchSize:     max(cfg.BatchSize, 1),
+		flushInterval: cfg.FlushInterval,
+		done:          make(chan struct{}),
+	}
+
+	go w.run()
+
+	defaultWriter.Store(w)
+
+	return w
+}
+
+// CurrentStats are the stats of the running writer, all zero when there is none.
+func CurrentStats() Stats {
+	if w := defaultWriter.Load(); w != nil {
+		return w.Stats()
+	}
+
+	return Stats{}
+}
+
+func (w *Writer) Stats() Stats {
+	return Stats{
+		Queued:  len(w.queue),
+		Dropped: w.dropped.Load(),
+		Failed:  w.failed.Load(),
+	}
+}
+
+// Record queues the event on the running writer, it is dropped when there is none, as in the command line tools.
+func Record(e *Event) {
+	if w := defaultWriter.Load(); w != nil {
+		w.Record(e)
+	}
+}
+
+// Record queues the event, dropping it when the queue is full rather than slowing the caller down.
+func (w *Writer) Record(e *Event) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return
+	}
+
+	select {
+	case w.queue <- e:
+	default:
+		if dropped := w.dropped.Add(1); dropped == 1 || dropped%1000 == 0 {
+			slog.Warn("Event queue is full, dropping events", "dropped", dropped)
+		}
+	}
+}
+
+// Close stops accepting events and waits until the queued ones are written, or ctx is done.
+func (w *Writer) Close(ctx context.Context) error {
+	defaultWriter.CompareAndSwap(w, nil)
+
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for %d queued events: %w", len(w.queue), ctx.Err())
+	}
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+
+	var flushTick <-chan time.Time
+	if w.flushInterval > 0 {
+		ticker := time.NewTicker(w.flushInterval)
+		defer ticker.Stop()
+
+		flushTick = ticker.C
+	}
+
+	batch := make([]*Event, 0, w.batchSize)
+
+	for {
+		select {
+		case e, ok := <-w.queue:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+
+			batch = append(batch, e)
+			if len(batch) >= w.batchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-flushTick:
+			w.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush writes the batch and what it tells about unknown hashes in a single transaction, a batch that can't be
+// written is logged and dropped.
+func (w *Writer) flush(batch []*Event) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := database.RetryOnBusy(ctx, func(ctx context.Context) error {
+		return database.WithinTransaction(ctx, func(ctx context.Context) error {
+			for _, e := range batch {
+				if err := eventRepository.Insert(ctx, e); err != nil {
+					return err
+				}
+			}
+
+			return recordUnknown(ctx, batch, time.Now().UnixNano())
+		})
+	})
+	if err != nil {
+		failed := w.failed.Add(int64(len(batch)))
+		slog.ErrorContext(ctx, "Failed to write events", "count", len(batch), "failed", failed, "error", err)
+	}
+}
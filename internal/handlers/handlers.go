@@ -1,10 +1,20 @@
 package handlers
 
 import (
+	"github.com/Gustrb/ccanalytics/internal/analytics"
 	"github.com/Gustrb/ccanalytics/internal/binsign"
+	"github.com/Gustrb/ccanalytics/internal/bundle"
+	"github.com/Gustrb/ccanalytics/internal/events"
+	"github.com/Gustrb/ccanalytics/internal/keys"
+	"github.com/Gustrb/ccanalytics/internal/tlog"
 	"github.com/labstack/echo/v5"
 )
 
 func Register(e *echo.Echo) {
+	analytics.Urls(e)
 	binsign.Urls(e)
+	bundle.Urls(e)
+	events.Urls(e)
+	keys.Urls(e)
+	tlog.Urls(e)
 }
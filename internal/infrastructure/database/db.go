@@ -7,33 +7,145 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 
+	"github.com/Gustrb/ccanalytics/internal/config"
 	"github.com/Gustrb/ccanalytics/internal/infrastructure/common"
 )
 
 var (
 	db *sql.DB
+	// connectedPath is kept for Settings, the driver doesn't expose it
+	connectedPath string
 )
 
 var (
 	ErrDBAlreadyConnected = fmt.Errorf("database connection already established")
 )
 
-func Connect(ctx context.Context, dataSourceName string) (common.CleanupFunction, error) {
+// querier is what *sql.DB and *sql.Tx have in common, helpers run against whichever one the context carries.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// rebind is left out for statements without arguments, migrations are sent as written.
+func rebind(query string, args []any) string {
+	if len(args) == 0 {
+		return query
+	}
+
+	return dialect.Rebind(query)
+}
+
+type txKey struct{}
+
+// lazyTx is only begun once something runs in it, a request that never reaches the database never takes the lock.
+type lazyTx struct {
+	ctx context.Context
+	mu  sync.Mutex
+	tx  *sql.Tx
+	// busy is set once a statement of the transaction failed with ErrBusy, see WithinTransaction
+	busy bool
+}
+
+func (l *lazyTx) get() (*sql.Tx, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.tx == nil {
+		tx, err := db.BeginTx(l.ctx, nil)
+		if err != nil {
+			err = classify(l.ctx, err)
+			l.busy = l.busy || errors.Is(err, ErrBusy)
+
+			return nil, fmt.Errorf("beginning transaction: %w", err)
+		}
+
+		l.tx = tx
+	}
+
+	return l.tx, nil
+}
+
+func (l *lazyTx) markBusy() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.busy = true
+}
+
+func (l *lazyTx) wasBusy() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.busy
+}
+
+func (l *lazyTx) begun() *sql.Tx {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.tx
+}
+
+// transaction is stored in the context by WithinTransaction, depth counts the savepoints opened on top of it.
+type transaction struct {
+	root  *lazyTx
+	depth int
+}
+
+func transactionFromContext(ctx context.Context) (*transaction, bool) {
+	t, ok := ctx.Value(txKey{}).(*transaction)
+	return t, ok
+}
+
+// conn returns the transaction carried by ctx, or the connection pool when there is none.
+func conn(ctx context.Context) (querier, error) {
+	if t, ok := transactionFromContext(ctx); ok {
+		return t.root.get()
+	}
+
+	return db, nil
+}
+
+func Connect(ctx context.Context, cfg config.DatabaseConfig) (common.CleanupFunction, error) {
 	if db != nil {
 		return nil, ErrDBAlreadyConnected
 	}
 
-	var err error
-	db, err = sql.Open("sqlite3", dataSourceName)
+	d, err := DialectFor(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	dataSourceName, err := d.DataSourceName(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err = sql.Open(d.DriverName(), dataSourceName)
 	if err != nil {
 		return nil, err
 	}
 
+	dialect = d
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	connectedPath = cfg.Path
+
 	cleanup := func() error {
-		return db.Close()
+		err := db.Close()
+		db = nil
+
+		return err
 	}
 
 	if err := db.PingContext(ctx); err != nil {
@@ -47,6 +159,15 @@ func Connect(ctx context.Context, dataSourceName string) (common.CleanupFunction
 	return cleanup, nil
 }
 
+// columnName is the sql struct tag of the field, or its lowercase name when it has none.
+func columnName(field reflect.StructField) string {
+	if name := field.Tag.Get("sql"); name != "" {
+		return name
+	}
+
+	return strings.ToLower(field.Name)
+}
+
 // getScanDest returns pointers to struct fields in the order of the given columns,
 // using sql struct tags (or lowercase field name) to match column names.
 func getScanDest(obj any, columns []string) ([]any, error) {
@@ -65,11 +186,7 @@ func getScanDest(obj any, columns []string) ([]any, error) {
 		if !field.IsExported() {
 			continue
 		}
-		colName := field.Tag.Get("sql")
-		if colName == "" {
-			colName = strings.ToLower(field.Name)
-		}
-		columnToField[colName] = i
+		columnToField[columnName(field)] = i
 	}
 
 	var dest []any
@@ -85,10 +202,15 @@ func getScanDest(obj any, columns []string) ([]any, error) {
 }
 
 func SelectContext[T any](ctx context.Context, query string, args ...any) ([]*T, error) {
-	rows, err := db.QueryContext(ctx, query, args...)
+	q, err := conn(ctx)
 	if err != nil {
 		return nil, err
 	}
+
+	rows, err := q.QueryContext(ctx, rebind(query, args), args...)
+	if err != nil {
+		return nil, classify(ctx, err)
+	}
 	defer rows.Close()
 
 	columns, err := rows.Columns()
@@ -114,6 +236,11 @@ func SelectContext[T any](ctx context.Context, query string, args ...any) ([]*T,
 		result = append(result, &o)
 	}
 
+	// statements with a returning clause only fail once their rows are read
+	if err := rows.Err(); err != nil {
+		return nil, classify(ctx, err)
+	}
+
 	return result, nil
 }
 
@@ -157,11 +284,29 @@ func InsertContext[T HasID](ctx context.Context, query string, obj T) error {
 		return err
 	}
 
-	result, err := db.ExecContext(ctx, query, args...)
+	q, err := conn(ctx)
 	if err != nil {
 		return err
 	}
 
+	if dialect.ReturningID() {
+		query = strings.TrimSuffix(strings.TrimSpace(query), ";") + " returning id;"
+
+		var id int
+		if err := q.QueryRowContext(ctx, rebind(query, args), args...).Scan(&id); err != nil {
+			return classify(ctx, err)
+		}
+
+		obj.SetID(id)
+
+		return nil
+	}
+
+	result, err := q.ExecContext(ctx, rebind(query, args), args...)
+	if err != nil {
+		return classify(ctx, err)
+	}
+
 	id, err := result.LastInsertId()
 	if err != nil {
 		return err
@@ -176,17 +321,50 @@ func PingContext(ctx context.Context) error {
 	return db.PingContext(ctx)
 }
 
-func ExecContext(ctx context.Context, query string) (sql.Result, error) {
-	return db.ExecContext(ctx, query)
+func ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	q, err := conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := q.ExecContext(ctx, rebind(query, args), args...)
+
+	return result, classify(ctx, err)
 }
 
+// WithinTransaction runs fn in a transaction that every helper called with fn's context takes part in.
+// Nested calls open a savepoint instead, so an inner failure only rolls back the inner work unless
+// the error is returned all the way up. A transaction that hit a busy error fails with ErrBusy even when fn
+// replaced that error with its own, the whole transaction has to be run again for the busy statement to succeed.
 func WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
+	if parent, ok := transactionFromContext(ctx); ok {
+		return withinSavepoint(ctx, parent, fn)
+	}
+
+	root := &lazyTx{ctx: ctx}
+
+	defer func() {
+		if p := recover(); p != nil {
+			if tx := root.begun(); tx != nil {
+				_ = tx.Rollback()
+			}
+
+			panic(p)
+		}
+	}()
+
+	err := fn(context.WithValue(ctx, txKey{}, &transaction{root: root}))
+	if err != nil && root.wasBusy() && !errors.Is(err, ErrBusy) {
+		err = &Error{Kind: ErrBusy, Err: err}
+	}
+
+	tx := root.begun()
+	if tx == nil {
+		// nothing ran in the transaction, there is nothing to commit
 		return err
 	}
 
-	if err := fn(ctx); err != nil {
+	if err != nil {
 		if rerr := tx.Rollback(); rerr != nil {
 			return fmt.Errorf("transaction function error: %w; also failed to rollback transaction: %v", err, rerr)
 		}
@@ -194,5 +372,53 @@ func WithinTransaction(ctx context.Context, fn func(ctx context.Context) error)
 		return err
 	}
 
-	return tx.Commit()
+	return classify(ctx, tx.Commit())
+}
+
+func withinSavepoint(ctx context.Context, parent *transaction, fn func(ctx context.Context) error) error {
+	t := &transaction{root: parent.root, depth: parent.depth + 1}
+	savepoint := fmt.Sprintf("sp_%d", t.depth)
+
+	tx, err := t.root.get()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "savepoint "+savepoint+";"); err != nil {
+		return fmt.Errorf("creating savepoint %s: %w", savepoint, err)
+	}
+
+	rollback := func() error {
+		// the rollback has to go through even when ctx is what made fn fail
+		ctx := context.WithoutCancel(ctx)
+
+		// rolling back to a savepoint keeps it open, it still has to be released
+		if _, err := tx.ExecContext(ctx, "rollback to savepoint "+savepoint+";"); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "release savepoint "+savepoint+";")
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, t)); err != nil {
+		if rerr := rollback(); rerr != nil {
+			return fmt.Errorf("transaction function error: %w; also failed to rollback to savepoint %s: %v", err, savepoint, rerr)
+		}
+
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "release savepoint "+savepoint+";"); err != nil {
+		return fmt.Errorf("releasing savepoint %s: %w", savepoint, err)
+	}
+
+	return nil
 }
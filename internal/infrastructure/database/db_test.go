@@ -0,0 +1,168 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type testRow struct {
+	ID   int    `sql:"id"`
+	Name string `sql:"name"`
+}
+
+func (r *testRow) GetID() int   { return r.ID }
+func (r *testRow) SetID(id int) { r.ID = id }
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	cleanup, err := Connect(ctx, config.DatabaseConfig{
+		Path:        filepath.Join(t.TempDir(), "test.db"),
+		JournalMode: "wal",
+		BusyTimeout: 50 * time.Millisecond,
+		ForeignKeys: true,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, cleanup())
+	})
+
+	_, err = ExecContext(ctx, "create table rows (id integer primary key autoincrement, name text not null);")
+	require.NoError(t, err)
+}
+
+func insertRow(ctx context.Context, name string) error {
+	return InsertContext(ctx, "insert into rows (name) values (?);", &testRow{Name: name})
+}
+
+func rowNames(t *testing.T) []string {
+	t.Helper()
+
+	rows, err := SelectContext[testRow](context.Background(), "select * from rows order by id;")
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, r := range rows {
+		names = append(names, r.Name)
+	}
+
+	return names
+}
+
+func TestShouldRollBackEveryHelperCalledWithinATransaction(t *testing.T) {
+	setupTestDB(t)
+
+	errFailed := errors.New("failed")
+
+	err := WithinTransaction(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, insertRow(ctx, "inserted"))
+
+		_, err := ExecContext(ctx, "update rows set name = 'updated';")
+		require.NoError(t, err)
+
+		// reads inside the transaction see its own writes
+		rows, err := SelectContext[testRow](ctx, "select * from rows;")
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		require.Equal(t, "updated", rows[0].Name)
+
+		return errFailed
+	})
+	require.ErrorIs(t, err, errFailed)
+	require.Empty(t, rowNames(t))
+}
+
+func TestShouldOnlyRollBackTheFailedSavepoint(t *testing.T) {
+	setupTestDB(t)
+
+	errFailed := errors.New("failed")
+
+	err := WithinTransaction(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, insertRow(ctx, "outer"))
+
+		err := WithinTransaction(ctx, func(ctx context.Context) error {
+			require.NoError(t, insertRow(ctx, "failed inner"))
+			return errFailed
+		})
+		require.ErrorIs(t, err, errFailed)
+
+		return WithinTransaction(ctx, func(ctx context.Context) error {
+			return insertRow(ctx, "inner")
+		})
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"outer", "inner"}, rowNames(t))
+}
+
+func TestShouldRollBackCommittedSavepointsWithTheTransaction(t *testing.T) {
+	setupTestDB(t)
+
+	errFailed := errors.New("failed")
+
+	err := WithinTransaction(context.Background(), func(ctx context.Context) error {
+		err := WithinTransaction(ctx, func(ctx context.Context) error {
+			return insertRow(ctx, "inner")
+		})
+		require.NoError(t, err)
+
+		return errFailed
+	})
+	require.ErrorIs(t, err, errFailed)
+	require.Empty(t, rowNames(t))
+}
+
+func TestShouldOnlyTakeTheWriteLockWhenLocking(t *testing.T) {
+	setupTestDB(t)
+
+	background := context.Background()
+
+	err := WithinTransaction(background, func(ctx context.Context) error {
+		_, err := SelectContext[testRow](ctx, "select * from rows;")
+		require.NoError(t, err)
+
+		// readers don't keep anyone from writing
+		return insertRow(background, "while reading")
+	})
+	require.NoError(t, err)
+
+	err = WithinTransaction(background, func(ctx context.Context) error {
+		require.NoError(t, LockTable(ctx, "rows"))
+
+		require.ErrorIs(t, insertRow(background, "while locked"), ErrBusy)
+
+		return insertRow(ctx, "locked")
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"while reading", "locked"}, rowNames(t))
+}
+
+func TestShouldFailTransactionsThatGotBusyWithErrBusy(t *testing.T) {
+	setupTestDB(t)
+
+	background := context.Background()
+	errHandled := errors.New("handled the busy error")
+
+	err := WithinTransaction(background, func(ctx context.Context) error {
+		_, err := SelectContext[testRow](ctx, "select * from rows;")
+		require.NoError(t, err)
+
+		// someone else commits after our read, our snapshot can't be written from anymore
+		require.NoError(t, insertRow(background, "committed in between"))
+		require.ErrorIs(t, insertRow(ctx, "stale"), ErrBusy)
+
+		// callers often answer with an error of their own, the transaction still has to be run again
+		return errHandled
+	})
+	require.ErrorIs(t, err, ErrBusy)
+	require.ErrorIs(t, err, errHandled)
+	require.Equal(t, []string{"committed in between"}, rowNames(t))
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+)
+
+var (
+	ErrUnknownDriver = errors.New("unknown database driver, expected sqlite or postgres")
+)
+
+// Dialect hides what differs between the databases we run on. Queries throughout the codebase are written
+// with ? placeholders and go through Rebind before reaching the driver.
+type Dialect interface {
+	// Name is the value of DATABASE_DRIVER, it also names the migrations directory of the dialect
+	Name() string
+	DriverName() string
+	DataSourceName(cfg config.DatabaseConfig) (string, error)
+	Rebind(query string) string
+	// Classify wraps the driver errors it knows about into an *Error, anything else is returned as is
+	Classify(err error) error
+	// ReturningID is true when the driver can't report the last inserted id and inserts need a returning clause
+	ReturningID() bool
+	// JSONTextField returns an expression for the text value of key in a JSON object column, with the
+	// argument its placeholder must be bound to
+	JSONTextField(column, key string) (string, any)
+	// LockTableQuery serializes writers of a table until the end of the transaction, empty when
+	// transactions are already serialized
+	LockTableQuery(table string) string
+	// ReadSettings fills in the settings in effect on the given connection
+	ReadSettings(ctx context.Context, c *sql.Conn, settings *Settings) error
+}
+
+var dialects = map[string]Dialect{
+	SQLite{}.Name():   SQLite{},
+	Postgres{}.Name(): Postgres{},
+}
+
+// dialect is the one in use, it is only replaced by Connect. It defaults to SQLite so errors can be classified
+// before a connection exists.
+var dialect Dialect = SQLite{}
+
+// DialectFor returns the dialect of the given DATABASE_DRIVER, SQLite when it is empty.
+func DialectFor(driver string) (Dialect, error) {
+	if driver == "" {
+		return SQLite{}, nil
+	}
+
+	d, ok := dialects[strings.ToLower(driver)]
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", driver, ErrUnknownDriver)
+	}
+
+	return d, nil
+}
+
+// CurrentDialect is the dialect of the open connection.
+func CurrentDialect() Dialect {
+	return dialect
+}
+
+// JSONTextField is CurrentDialect().JSONTextField, for building where clauses on JSON columns.
+func JSONTextField(column, key string) (string, any) {
+	return dialect.JSONTextField(column, key)
+}
+
+// LockTable keeps concurrent transactions from writing to table until the one in ctx ends, it must be called
+// within a transaction and before the transaction reads anything it is going to write back.
+func LockTable(ctx context.Context, table string) error {
+	if _, ok := transactionFromContext(ctx); !ok {
+		return fmt.Errorf("locking %s: not within a transaction", table)
+	}
+
+	query := dialect.LockTableQuery(table)
+	if query == "" {
+		return nil
+	}
+
+	_, err := ExecContext(ctx, query)
+
+	return err
+}
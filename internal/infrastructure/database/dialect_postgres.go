@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDatabaseURLRequired = errors.New("database url is required for postgres")
+)
+
+// SQLSTATE codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgUniqueViolation      = "23505"
+	pgForeignKeyViolation  = "23503"
+	pgCheckViolation       = "23514"
+	pgNotNullViolation     = "23502"
+	pgUndefinedTable       = "42P01"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	pgLockNotAvailable     = "55P03"
+)
+
+// Postgres is backed by lib/pq.
+type Postgres struct{}
+
+func (Postgres) Name() string {
+	return "postgres"
+}
+
+func (Postgres) DriverName() string {
+	return "postgres"
+}
+
+func (Postgres) DataSourceName(cfg config.DatabaseConfig) (string, error) {
+	if cfg.URL == "" {
+		return "", ErrDatabaseURLRequired
+	}
+
+	return cfg.URL, nil
+}
+
+// Rebind numbers the ? placeholders as $1, $2... leaving the ones in quoted literals and identifiers alone.
+func (Postgres) Rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	var quote rune
+
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			// a doubled quote closes and reopens the literal, which leaves it open as it should
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func (Postgres) Classify(err error) error {
+	pqErr, ok := errors.AsType[*pq.Error](err)
+	if !ok {
+		return err
+	}
+
+	var kind error
+
+	switch pqErr.Code {
+	case pgUniqueViolation:
+		kind = ErrUniqueViolation
+	case pgForeignKeyViolation:
+		kind = ErrForeignKeyViolation
+	case pgCheckViolation:
+		kind = ErrCheckViolation
+	case pgNotNullViolation:
+		kind = ErrNotNullViolation
+	case pgSerializationFailure, pgDeadlockDetected, pgLockNotAvailable:
+		kind = ErrBusy
+	case pgUndefinedTable:
+		kind = ErrNoSuchTable
+	default:
+		return err
+	}
+
+	return &Error{Kind: kind, Err: err}
+}
+
+func (Postgres) ReturningID() bool {
+	return true
+}
+
+func (Postgres) JSONTextField(column, key string) (string, any) {
+	return column + " ->> ?", key
+}
+
+func (Postgres) LockTableQuery(table string) string {
+	// conflicts with itself and with every write, plain reads still go through
+	return "lock table " + table + " in share row exclusive mode;"
+}
+
+func (Postgres) ReadSettings(ctx context.Context, c *sql.Conn, settings *Settings) error {
+	return c.QueryRowContext(ctx, "select current_database();").Scan(&settings.Path)
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	journalModes      = []string{"delete", "truncate", "persist", "memory", "wal", "off"}
+	synchronousLevels = []string{"off", "normal", "full", "extra"}
+)
+
+var (
+	ErrDatabasePathRequired = errors.New("database path is required")
+	ErrInvalidJournalMode   = errors.New("invalid journal mode, expected one of delete, truncate, persist, memory, wal or off")
+	ErrInvalidSynchronous   = errors.New("invalid synchronous level, expected one of off, normal, full or extra")
+)
+
+// SQLite is the default dialect, backed by mattn/go-sqlite3.
+type SQLite struct{}
+
+func (SQLite) Name() string {
+	return "sqlite"
+}
+
+func (SQLite) DriverName() string {
+	return "sqlite3"
+}
+
+// DataSourceName turns the config into the pragmas mattn/go-sqlite3 applies on every new connection.
+func (SQLite) DataSourceName(cfg config.DatabaseConfig) (string, error) {
+	if cfg.Path == "" {
+		return "", ErrDatabasePathRequired
+	}
+
+	// Transactions are deferred, readers never take the write lock. A transaction that reads and then writes can't
+	// wait for a writer that committed in between, so SQLite fails it right away, those take the lock up front with
+	// LockTable.
+	params := url.Values{}
+
+	if cfg.JournalMode != "" {
+		mode := strings.ToLower(cfg.JournalMode)
+		if !slices.Contains(journalModes, mode) {
+			return "", fmt.Errorf("%q: %w", cfg.JournalMode, ErrInvalidJournalMode)
+		}
+
+		params.Set("_journal_mode", strings.ToUpper(mode))
+	}
+
+	if cfg.Synchronous != "" {
+		level := strings.ToLower(cfg.Synchronous)
+		if !slices.Contains(synchronousLevels, level) {
+			return "", fmt.Errorf("%q: %w", cfg.Synchronous, ErrInvalidSynchronous)
+		}
+
+		params.Set("_synchronous", strings.ToUpper(level))
+	}
+
+	if cfg.BusyTimeout > 0 {
+		params.Set("_busy_timeout", strconv.FormatInt(cfg.BusyTimeout.Milliseconds(), 10))
+	}
+
+	foreignKeys := "0"
+	if cfg.ForeignKeys {
+		foreignKeys = "1"
+	}
+	params.Set("_foreign_keys", foreignKeys)
+
+	return cfg.Path + "?" + params.Encode(), nil
+}
+
+func (SQLite) Rebind(query string) string {
+	return query
+}
+
+func (SQLite) Classify(err error) error {
+	sqliteErr, ok := errors.AsType[sqlite3.Error](err)
+	if !ok {
+		return err
+	}
+
+	var kind error
+
+	switch {
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique, sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+		kind = ErrUniqueViolation
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey:
+		kind = ErrForeignKeyViolation
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintCheck:
+		kind = ErrCheckViolation
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintNotNull:
+		kind = ErrNotNullViolation
+	case sqliteErr.Code == sqlite3.ErrBusy, sqliteErr.Code == sqlite3.ErrLocked:
+		kind = ErrBusy
+	// SQLite has no dedicated code for a missing table, it is a generic error told apart by its message
+	case sqliteErr.Code == sqlite3.ErrError && strings.HasPrefix(sqliteErr.Error(), "no such table"):
+		kind = ErrNoSuchTable
+	default:
+		return err
+	}
+
+	return &Error{Kind: kind, Err: err}
+}
+
+func (SQLite) ReturningID() bool {
+	return false
+}
+
+func (SQLite) JSONTextField(column, key string) (string, any) {
+	// the key is quoted in the JSON path so dots in it are not read as nesting
+	return "json_extract(" + column + ", ?)", `$."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+}
+
+// LockTableQuery locks the whole database, SQLite has no other write lock. A write that matches no row is enough
+// to take it, waiting for the busy timeout like any other write.
+func (SQLite) LockTableQuery(table string) string {
+	return "delete from " + table + " where 0;"
+}
+
+func (SQLite) ReadSettings(ctx context.Context, c *sql.Conn, settings *Settings) error {
+	var synchronous int
+	var foreignKeys int
+
+	pragmas := []struct {
+		name string
+		dest any
+	}{
+		{"journal_mode", &settings.JournalMode},
+		{"busy_timeout", &settings.BusyTimeoutMS},
+		{"synchronous", &synchronous},
+		{"foreign_keys", &foreignKeys},
+	}
+
+	for _, pragma := range pragmas {
+		if err := c.QueryRowContext(ctx, "pragma "+pragma.name+";").Scan(pragma.dest); err != nil {
+			return fmt.Errorf("reading pragma %s: %w", pragma.name, err)
+		}
+	}
+
+	if synchronous >= 0 && synchronous < len(synchronousLevels) {
+		settings.Synchronous = synchronousLevels[synchronous]
+	}
+	settings.ForeignKeys = foreignKeys == 1
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldPickTheDialectOfTheDriver(t *testing.T) {
+	d, err := DialectFor("")
+	require.NoError(t, err)
+	require.Equal(t, SQLite{}, d)
+
+	d, err = DialectFor("Postgres")
+	require.NoError(t, err)
+	require.Equal(t, Postgres{}, d)
+
+	_, err = DialectFor("mysql")
+	require.ErrorIs(t, err, ErrUnknownDriver)
+}
+
+func TestShouldRebindPlaceholdersForPostgres(t *testing.T) {
+	require.Equal(t,
+		"select * from signed_binaries where hash = $1 and os = $2;",
+		Postgres{}.Rebind("select * from signed_binaries where hash = ? and os = ?;"),
+	)
+
+	// question marks in literals and quoted identifiers are not placeholders
+	require.Equal(t,
+		`select '?', "what?" from t where a = $1 and b = 'it''s ?' and c = $2;`,
+		Postgres{}.Rebind(`select '?', "what?" from t where a = ? and b = 'it''s ?' and c = ?;`),
+	)
+
+	require.Equal(t, "select 1;", SQLite{}.Rebind("select 1;"))
+}
+
+func TestShouldClassifyPostgresErrors(t *testing.T) {
+	err := Postgres{}.Classify(&pq.Error{Code: pgUniqueViolation})
+	require.ErrorIs(t, err, ErrUniqueViolation)
+
+	pqErr, ok := errors.AsType[*pq.Error](err)
+	require.True(t, ok)
+	require.Equal(t, pq.ErrorCode(pgUniqueViolation), pqErr.Code)
+
+	require.ErrorIs(t, Postgres{}.Classify(&pq.Error{Code: pgDeadlockDetected}), ErrBusy)
+	require.ErrorIs(t, Postgres{}.Classify(&pq.Error{Code: pgUndefinedTable}), ErrNoSuchTable)
+
+	other := &pq.Error{Code: "22012"}
+	require.Equal(t, error(other), Postgres{}.Classify(other))
+}
+
+func TestShouldRequireAURLForPostgres(t *testing.T) {
+	_, err := Postgres{}.DataSourceName(config.DatabaseConfig{Path: "app.db"})
+	require.ErrorIs(t, err, ErrDatabaseURLRequired)
+
+	dsn, err := Postgres{}.DataSourceName(config.DatabaseConfig{URL: "postgres://localhost/ccanalytics"})
+	require.NoError(t, err)
+	require.Equal(t, "postgres://localhost/ccanalytics", dsn)
+}
+
+func TestShouldBuildJSONFieldExpressions(t *testing.T) {
+	expr, arg := SQLite{}.JSONTextField("labels", "team")
+	require.Equal(t, "json_extract(labels, ?)", expr)
+	require.Equal(t, `$."team"`, arg)
+
+	expr, arg = Postgres{}.JSONTextField("labels", "team")
+	require.Equal(t, "labels ->> ?", expr)
+	require.Equal(t, "team", arg)
+}
@@ -1,27 +1,77 @@
 package database
 
-import "strings"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
-func IsNoTableFoundError(err error) bool {
+// Errors returned by the helpers of this package are classified against these sentinels, check them with errors.Is.
+var (
+	ErrUniqueViolation     = errors.New("unique constraint violation")
+	ErrForeignKeyViolation = errors.New("foreign key constraint violation")
+	ErrCheckViolation      = errors.New("check constraint violation")
+	ErrNotNullViolation    = errors.New("not null constraint violation")
+	ErrBusy                = errors.New("database is busy")
+	ErrNoSuchTable         = errors.New("no such table")
+)
+
+const (
+	busyRetries     = 5
+	busyBaseBackoff = 50 * time.Millisecond
+)
+
+// Error is a driver error along with the sentinel it was classified as, the driver error is still reachable
+// through errors.As for anything that needs the raw codes.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// classify lets the dialect wrap the driver errors it knows about into an *Error. A busy error is also noted on
+// the transaction ctx carries, whatever fn makes of it.
+func classify(ctx context.Context, err error) error {
 	if err == nil {
-		return false
+		return nil
+	}
+
+	if _, ok := errors.AsType[*Error](err); !ok {
+		err = dialect.Classify(err)
 	}
 
-	if strings.Contains(err.Error(), "no such table") {
-		return true
+	if t, ok := transactionFromContext(ctx); ok && errors.Is(err, ErrBusy) {
+		t.root.markBusy()
 	}
 
-	return false
+	return err
 }
 
-func IsDuplicateEntryError(err error) bool {
-	if err == nil {
-		return false
+// RetryOnBusy runs fn again, with a growing backoff, for as long as it fails with ErrBusy and retries are left.
+// Inside a transaction fn runs once, the busy statement can't be retried without retrying the whole transaction,
+// which is up to whoever began it (rest.WithTransaction does so for requests).
+func RetryOnBusy(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := transactionFromContext(ctx); ok {
+		return fn(ctx)
 	}
 
-	if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-		return true
-	}
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if attempt >= busyRetries || !errors.Is(err, ErrBusy) {
+			return err
+		}
 
-	return false
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(busyBaseBackoff << attempt):
+		}
+	}
 }
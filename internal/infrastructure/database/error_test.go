@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldClassifyConstraintErrors(t *testing.T) {
+	ctx := context.Background()
+	setupTestDB(t)
+
+	_, err := ExecContext(ctx, "create table checked (name text not null unique, amount integer check (amount > 0));")
+	require.NoError(t, err)
+
+	_, err = ExecContext(ctx, "insert into checked (name, amount) values ('a', 1);")
+	require.NoError(t, err)
+
+	_, err = ExecContext(ctx, "insert into checked (name, amount) values ('a', 1);")
+	require.ErrorIs(t, err, ErrUniqueViolation)
+
+	_, err = ExecContext(ctx, "insert into checked (name, amount) values ('b', 0);")
+	require.ErrorIs(t, err, ErrCheckViolation)
+
+	_, err = ExecContext(ctx, "insert into checked (name, amount) values (null, 1);")
+	require.ErrorIs(t, err, ErrNotNullViolation)
+
+	_, err = SelectContext[testRow](ctx, "select * from missing;")
+	require.ErrorIs(t, err, ErrNoSuchTable)
+
+	// the driver error stays reachable
+	sqliteErr, ok := errors.AsType[sqlite3.Error](err)
+	require.True(t, ok)
+	require.Equal(t, sqlite3.ErrError, sqliteErr.Code)
+}
+
+func TestShouldRetryBusyErrors(t *testing.T) {
+	ctx := context.Background()
+	busy := classify(ctx, sqlite3.Error{Code: sqlite3.ErrBusy})
+	require.ErrorIs(t, busy, ErrBusy)
+
+	attempts := 0
+	err := RetryOnBusy(ctx, func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return busy
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = RetryOnBusy(ctx, func(ctx context.Context) error {
+		attempts++
+		return ErrUniqueViolation
+	})
+	require.ErrorIs(t, err, ErrUniqueViolation)
+	require.Equal(t, 1, attempts)
+}
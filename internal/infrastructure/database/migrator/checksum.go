@@ -0,0 +1,134 @@
+package migrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/migrations"
+)
+
+var (
+	ErrMigrationDrift = errors.New("applied migrations differ from the ones in the codebase")
+)
+
+// checksum is the sha256 of the up section, line endings and surrounding whitespace don't count as edits.
+func checksum(up string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(strings.ReplaceAll(up, "\r\n", "\n"))))
+	return hex.EncodeToString(sum[:])
+}
+
+type DriftKind string
+
+const (
+	// DriftEdited is an applied migration whose up section changed since it was applied
+	DriftEdited DriftKind = "edited"
+	// DriftMissing is an applied migration whose file is no longer in the codebase
+	DriftMissing DriftKind = "missing"
+	// DriftUnrecorded is an applied migration with no checksum to compare against, migrate up records it
+	DriftUnrecorded DriftKind = "unrecorded"
+)
+
+type Drift struct {
+	Kind         DriftKind `json:"kind"`
+	Filename     string    `json:"filename"`
+	Timestamp    int       `json:"timestamp"`
+	RecordedHash string    `json:"recorded_hash,omitempty"`
+	CurrentHash  string    `json:"current_hash,omitempty"`
+}
+
+// String fits on one line, so a list of drifts reads like a diff stat.
+func (d Drift) String() string {
+	switch d.Kind {
+	case DriftEdited:
+		return fmt.Sprintf("%s: edited after it was applied, recorded %s, file %s", d.Filename, d.RecordedHash, d.CurrentHash)
+	case DriftMissing:
+		return fmt.Sprintf("%s: applied but no longer in the codebase, recorded %s", d.Filename, d.RecordedHash)
+	default:
+		return fmt.Sprintf("%s: applied before checksums were recorded, file %s", d.Filename, d.CurrentHash)
+	}
+}
+
+func (h *history) drifts() []Drift {
+	var drifts []Drift
+
+	for _, applied := range h.applied {
+		d := Drift{Filename: applied.Filename, Timestamp: int(applied.Timestamp), RecordedHash: applied.Hash}
+
+		change, ok := h.byTime[d.Timestamp]
+		switch {
+		case !ok:
+			d.Kind = DriftMissing
+		case applied.Hash == "":
+			d.Kind = DriftUnrecorded
+			d.CurrentHash = change.Hash
+		case applied.Hash != change.Hash:
+			d.Kind = DriftEdited
+			d.Filename = change.Filename
+			d.CurrentHash = change.Hash
+		default:
+			continue
+		}
+
+		drifts = append(drifts, d)
+	}
+
+	return drifts
+}
+
+// checkDrift fails when an applied migration was edited, migrations without a recorded checksum are let through.
+func (h *history) checkDrift() error {
+	var lines []string
+	for _, d := range h.drifts() {
+		if d.Kind == DriftEdited {
+			lines = append(lines, "  "+d.String())
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w, restore the files and add a new migration instead:\n%s", ErrMigrationDrift, strings.Join(lines, "\n"))
+}
+
+// Verify reports every applied migration that doesn't match the codebase, without changing anything.
+func Verify(ctx context.Context) ([]Drift, error) {
+	h, err := loadHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.drifts(), nil
+}
+
+// recordChecksums fills in the hash of migrations applied before the migrations table had a column for it, bootstrap
+// adds the column first. Their files may have been edited since, the current ones are trusted.
+func (h *history) recordChecksums(ctx context.Context) error {
+	if len(h.applied) == 0 {
+		return nil
+	}
+
+	return database.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, applied := range h.applied {
+			change, ok := h.byTime[int(applied.Timestamp)]
+			if applied.Hash != "" || !ok {
+				continue
+			}
+
+			applied.Hash = change.Hash
+			if err := migrations.Update(ctx, applied); err != nil {
+				return fmt.Errorf("recording checksum of %s: %w", applied.Filename, err)
+			}
+
+			slog.InfoContext(ctx, "recorded migration checksum", "filename", applied.Filename, "hash", applied.Hash)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,78 @@
+package migrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldIgnoreWhitespaceInChecksums(t *testing.T) {
+	require.Equal(t, checksum("\ncreate table t (id integer);\n"), checksum("create table t (id integer);\r\n\r\n"))
+	require.NotEqual(t, checksum("create table t (id integer);"), checksum("create table t (id text);"))
+}
+
+func TestShouldDetectEditedMigrations(t *testing.T) {
+	ctx := context.Background()
+	setupTestDB(t)
+
+	require.NoError(t, MigrateUp(ctx))
+
+	drifts, err := Verify(ctx)
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+
+	changes, err := parseMigrations()
+	require.NoError(t, err)
+
+	_, err = database.ExecContext(ctx, "update migrations set hash = ? where timestamp = ?;", "edited", changes[1].Timestamp)
+	require.NoError(t, err)
+
+	_, err = AreWeAtTheLatestMigration(ctx)
+	require.ErrorIs(t, err, ErrMigrationDrift)
+	require.ErrorContains(t, err, changes[1].Filename)
+
+	require.ErrorIs(t, MigrateUp(ctx), ErrMigrationDrift)
+
+	drifts, err = Verify(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []Drift{{
+		Kind:         DriftEdited,
+		Filename:     changes[1].Filename,
+		Timestamp:    changes[1].Timestamp,
+		RecordedHash: "edited",
+		CurrentHash:  changes[1].Hash,
+	}}, drifts)
+}
+
+func TestShouldRecordChecksumsOfMigrationsAppliedWithoutThem(t *testing.T) {
+	ctx := context.Background()
+	setupTestDB(t)
+
+	require.NoError(t, MigrateUp(ctx))
+
+	// databases migrated before checksums were recorded have neither the hash column nor the migration adding it
+	_, err := database.ExecContext(ctx, "alter table migrations drop column hash;")
+	require.NoError(t, err)
+
+	_, err = database.ExecContext(ctx, "delete from migrations where timestamp = ?;", addHashToMigrationsTimestamp)
+	require.NoError(t, err)
+
+	latest, err := AreWeAtTheLatestMigration(ctx)
+	require.NoError(t, err)
+	require.False(t, latest)
+
+	drifts, err := Verify(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, drifts)
+	for _, d := range drifts {
+		require.Equal(t, DriftUnrecorded, d.Kind)
+	}
+
+	require.NoError(t, MigrateUp(ctx))
+
+	drifts, err = Verify(ctx)
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+}
@@ -0,0 +1,99 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SourceDir is where the migrations are embedded from, relative to the root of the repository.
+const SourceDir = "internal/infrastructure/database/migrator/migrations"
+
+const migrationTemplate = `-- migrate up
+
+-- migrate down
+`
+
+var (
+	ErrInvalidMigrationName = errors.New("migration name must contain at least one letter or digit")
+	ErrNoDialectDirectories = errors.New("no dialect directories found")
+)
+
+var (
+	nonSlugReg, _ = regexp.Compile(`[^a-z0-9]+`)
+)
+
+// Create writes an empty migration named after name into every dialect directory of dir, all of them sharing
+// one timestamp. The timestamp is now, or right after the newest existing migration when that one is later,
+// so the new migration always sorts last.
+func Create(dir, name string) ([]string, error) {
+	slug := strings.Trim(nonSlugReg.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		return nil, fmt.Errorf("%q: %w", name, ErrInvalidMigrationName)
+	}
+
+	fsys := os.DirFS(dir)
+
+	dialects, err := dialectDirs(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dialects) == 0 {
+		return nil, fmt.Errorf("%s: %w", dir, ErrNoDialectDirectories)
+	}
+
+	timestamp := int(time.Now().Unix())
+
+	existing, err := fs.Glob(fsys, "*/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("globbing migrations: %w", err)
+	}
+
+	for _, filename := range existing {
+		m, err := readMigration(fsys, filename)
+		if err != nil {
+			return nil, err
+		}
+
+		timestamp = max(timestamp, m.Timestamp+1)
+	}
+
+	for _, m := range registry {
+		timestamp = max(timestamp, m.Timestamp+1)
+	}
+
+	var created []string
+	for _, dialect := range dialects {
+		filename := filepath.Join(dir, dialect, fmt.Sprintf("%d-%s.sql", timestamp, slug))
+
+		if err := os.WriteFile(filename, []byte(migrationTemplate), 0o644); err != nil {
+			return created, fmt.Errorf("writing migration %s: %w", filename, err)
+		}
+
+		created = append(created, filename)
+	}
+
+	return created, nil
+}
+
+func dialectDirs(fsys fs.FS) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations directory: %w", err)
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+
+	return dirs, nil
+}
@@ -0,0 +1,159 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Gustrb/ccanalytics/internal/migrations"
+)
+
+var (
+	ErrMigrationsTableRollback = errors.New("refusing to roll back a migration of the migrations table")
+	ErrIrreversibleMigration   = errors.New("migration has no down section or function")
+	ErrUnknownMigration        = errors.New("no migration with this timestamp")
+	ErrNothingToRollBack       = errors.New("no migration has been applied")
+)
+
+// downStep checks that the applied migration can be rolled back.
+func (h *history) downStep(applied *migrations.Migration) (step, error) {
+	migration, ok := h.byTime[int(applied.Timestamp)]
+	if !ok {
+		return step{}, fmt.Errorf("%s: migration file is missing from the codebase", applied.Filename)
+	}
+
+	if !migration.reversible() {
+		return step{}, fmt.Errorf("%s: %w", migration.Filename, ErrIrreversibleMigration)
+	}
+
+	return step{migration: migration, direction: directionDown, applied: applied}, nil
+}
+
+// MigrateDown rolls back the last n applied migrations, newest first. The migrations of the table the records live
+// in never are, asking to go past them refuses the whole batch.
+func MigrateDown(ctx context.Context, n int) error {
+	h, err := loadHistory(ctx)
+	if err != nil {
+		return err
+	}
+
+	// checked before bootstrapping, which would apply the migrations of the migrations table on an empty database
+	if len(h.applied) == 0 {
+		return ErrNothingToRollBack
+	}
+
+	h, err = bootstrap(ctx)
+	if err != nil {
+		return err
+	}
+
+	rollbackable := h.rollbackable()
+	if n = min(n, len(h.applied)); n > len(rollbackable) {
+		return fmt.Errorf("rolling back %d migrations: %w", n, ErrMigrationsTableRollback)
+	}
+
+	steps := make([]step, 0, n)
+	for i := len(rollbackable) - 1; i >= len(rollbackable)-n; i-- {
+		s, err := h.downStep(rollbackable[i])
+		if err != nil {
+			return err
+		}
+
+		steps = append(steps, s)
+	}
+
+	if err := runSteps(ctx, steps); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Successfully rolled back migrations", "count", len(steps))
+
+	return nil
+}
+
+// MigrateTo applies or rolls back migrations until timestamp is the last one applied, the migrations of the
+// migrations table stay applied whatever it is.
+func MigrateTo(ctx context.Context, timestamp int) error {
+	h, err := bootstrap(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := h.byTime[timestamp]; !ok {
+		return fmt.Errorf("%d: %w", timestamp, ErrUnknownMigration)
+	}
+
+	var steps []step
+
+	rollbackable := h.rollbackable()
+	for i := len(rollbackable) - 1; i >= 0; i-- {
+		if int(rollbackable[i].Timestamp) <= timestamp {
+			continue
+		}
+
+		s, err := h.downStep(rollbackable[i])
+		if err != nil {
+			return err
+		}
+
+		steps = append(steps, s)
+	}
+
+	for _, change := range h.changes {
+		if change.Timestamp > timestamp || h.isApplied(change.Timestamp) {
+			continue
+		}
+
+		steps = append(steps, step{migration: change, direction: directionUp})
+	}
+
+	if len(steps) == 0 {
+		slog.InfoContext(ctx, "Already at the requested migration", "timestamp", timestamp)
+		return nil
+	}
+
+	if err := runSteps(ctx, steps); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Successfully migrated", "timestamp", timestamp, "steps", len(steps))
+
+	return nil
+}
+
+// Redo rolls back the last applied migration and applies it again, in the same transaction.
+func Redo(ctx context.Context) error {
+	h, err := loadHistory(ctx)
+	if err != nil {
+		return err
+	}
+
+	// checked before bootstrapping, which would apply the migrations of the migrations table on an empty database
+	if len(h.applied) == 0 {
+		return ErrNothingToRollBack
+	}
+
+	h, err = bootstrap(ctx)
+	if err != nil {
+		return err
+	}
+
+	rollbackable := h.rollbackable()
+	if len(rollbackable) == 0 {
+		return ErrMigrationsTableRollback
+	}
+
+	down, err := h.downStep(rollbackable[len(rollbackable)-1])
+	if err != nil {
+		return err
+	}
+
+	if err := runSteps(ctx, []step{down, {migration: down.migration, direction: directionUp}}); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Successfully redid migration", "filename", down.migration.Filename)
+
+	return nil
+}
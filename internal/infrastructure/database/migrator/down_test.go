@@ -0,0 +1,132 @@
+package migrator
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/migrations"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+
+	cleanup, err := database.Connect(context.Background(), config.DatabaseConfig{
+		Path:        filepath.Join(t.TempDir(), "test.db"),
+		JournalMode: "wal",
+		ForeignKeys: true,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, cleanup())
+	})
+}
+
+func appliedCount(t *testing.T, ctx context.Context) int {
+	t.Helper()
+
+	statuses, err := Status(ctx)
+	require.NoError(t, err)
+
+	count := 0
+	for _, s := range statuses {
+		if s.Applied {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestShouldRollBackAndReapplyMigrations(t *testing.T) {
+	ctx := context.Background()
+	setupTestDB(t)
+
+	changes, err := parseMigrations()
+	require.NoError(t, err)
+
+	require.NoError(t, MigrateUp(ctx))
+	require.Equal(t, len(changes), appliedCount(t, ctx))
+
+	require.NoError(t, MigrateDown(ctx, 2))
+	require.Equal(t, len(changes)-2, appliedCount(t, ctx))
+
+	latest, err := AreWeAtTheLatestMigration(ctx)
+	require.NoError(t, err)
+	require.False(t, latest)
+
+	require.NoError(t, Redo(ctx))
+	require.Equal(t, len(changes)-2, appliedCount(t, ctx))
+
+	// the hash column of the migrations table stays
+	require.NoError(t, MigrateTo(ctx, changes[1].Timestamp))
+	require.Equal(t, 3, appliedCount(t, ctx))
+
+	require.NoError(t, MigrateTo(ctx, changes[len(changes)-1].Timestamp))
+	require.Equal(t, len(changes), appliedCount(t, ctx))
+
+	require.ErrorIs(t, MigrateTo(ctx, 42), ErrUnknownMigration)
+}
+
+func TestShouldRefuseToRollBackTheMigrationsTable(t *testing.T) {
+	ctx := context.Background()
+	setupTestDB(t)
+
+	require.ErrorIs(t, MigrateDown(ctx, 1), ErrNothingToRollBack)
+
+	require.NoError(t, MigrateUp(ctx))
+
+	changes, err := parseMigrations()
+	require.NoError(t, err)
+
+	// the whole batch is refused, nothing is rolled back
+	require.ErrorIs(t, MigrateDown(ctx, len(changes)), ErrMigrationsTableRollback)
+	require.Equal(t, len(changes), appliedCount(t, ctx))
+
+	require.NoError(t, MigrateTo(ctx, changes[0].Timestamp))
+	require.Equal(t, 2, appliedCount(t, ctx))
+
+	require.ErrorIs(t, Redo(ctx), ErrMigrationsTableRollback)
+}
+
+func TestShouldOnlyApplyOutOfOrderMigrationsWhenAllowed(t *testing.T) {
+	ctx := context.Background()
+	setupTestDB(t)
+
+	changes, err := parseMigrations()
+	require.NoError(t, err)
+
+	require.NoError(t, MigrateTo(ctx, changes[1].Timestamp))
+
+	// a newer build applied a migration this one doesn't know about, everything pending is now older than it
+	unknown := migrations.NewMigration(
+		migrations.WithFilename("migrations/sqlite/9999999999-from-the-future.sql"),
+		migrations.WithTimestamp(9999999999),
+	)
+	_, err = migrations.Create(ctx, unknown)
+	require.NoError(t, err)
+
+	latest, err := AreWeAtTheLatestMigration(ctx)
+	require.NoError(t, err)
+	require.False(t, latest)
+
+	// the hash column of the migrations table is added ahead of the others all the same
+	require.ErrorIs(t, MigrateUp(ctx), ErrOutOfOrderMigration)
+	require.Equal(t, 4, appliedCount(t, ctx))
+
+	require.NoError(t, MigrateUp(ctx, WithAllowOutOfOrder(true)))
+	require.Equal(t, len(changes)+1, appliedCount(t, ctx))
+
+	latest, err = AreWeAtTheLatestMigration(ctx)
+	require.NoError(t, err)
+	require.True(t, latest)
+
+	drifts, err := Verify(ctx)
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	require.Equal(t, DriftMissing, drifts[0].Kind)
+}
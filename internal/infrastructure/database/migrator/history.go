@@ -0,0 +1,131 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Gustrb/ccanalytics/internal/migrations"
+)
+
+// history pairs the migrations in the codebase with the records of the ones applied to the database.
+type history struct {
+	changes []*Migration
+	applied []*migrations.Migration
+	byTime  map[int]*Migration
+}
+
+func loadHistory(ctx context.Context) (*history, error) {
+	changes, err := parseMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := migrations.GetLatestAppliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &history{changes: changes, applied: applied, byTime: make(map[int]*Migration, len(changes))}
+	for _, change := range changes {
+		h.byTime[change.Timestamp] = change
+	}
+
+	return h, nil
+}
+
+func (h *history) isApplied(timestamp int) bool {
+	for _, a := range h.applied {
+		if int(a.Timestamp) == timestamp {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pending are the migrations in the codebase that haven't been applied, oldest first.
+func (h *history) pending() []*Migration {
+	var pending []*Migration
+	for _, change := range h.changes {
+		if !h.isApplied(change.Timestamp) {
+			pending = append(pending, change)
+		}
+	}
+
+	return pending
+}
+
+// unknown are the applied migrations that aren't in the codebase, usually applied by a newer build.
+func (h *history) unknown() []*migrations.Migration {
+	var unknown []*migrations.Migration
+	for _, applied := range h.applied {
+		if _, ok := h.byTime[int(applied.Timestamp)]; !ok {
+			unknown = append(unknown, applied)
+		}
+	}
+
+	return unknown
+}
+
+// isBootstrap is true for the migrations that shape the migrations table itself, creating it and adding the
+// hash column. They are applied before any other, so every record has a place for its checksum, and never rolled
+// back.
+func (h *history) isBootstrap(timestamp int) bool {
+	return (len(h.changes) > 0 && timestamp == h.changes[0].Timestamp) || timestamp == addHashToMigrationsTimestamp
+}
+
+// rollbackable are the applied migrations, oldest first, but the ones of the migrations table.
+func (h *history) rollbackable() []*migrations.Migration {
+	var rollbackable []*migrations.Migration
+	for _, applied := range h.applied {
+		if !h.isBootstrap(int(applied.Timestamp)) {
+			rollbackable = append(rollbackable, applied)
+		}
+	}
+
+	return rollbackable
+}
+
+// lastApplied is the timestamp of the newest applied migration, zero when none is. The migrations of the
+// migrations table don't count, they are applied ahead of their turn.
+func (h *history) lastApplied() int {
+	rollbackable := h.rollbackable()
+	if len(rollbackable) == 0 {
+		return 0
+	}
+
+	return int(rollbackable[len(rollbackable)-1].Timestamp)
+}
+
+// bootstrap applies the migrations of the migrations table that are still pending, then records the checksums of
+// the migrations applied before there was a column for them. It returns the history as it is afterwards.
+func bootstrap(ctx context.Context) (*history, error) {
+	h, err := loadHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []step
+	for _, change := range h.pending() {
+		if h.isBootstrap(change.Timestamp) {
+			steps = append(steps, step{migration: change, direction: directionUp})
+		}
+	}
+
+	if len(steps) > 0 {
+		if err := runSteps(ctx, steps); err != nil {
+			return nil, fmt.Errorf("applying the migrations table migrations: %w", err)
+		}
+
+		h, err = loadHistory(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.recordChecksums(ctx); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
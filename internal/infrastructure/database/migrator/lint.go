@@ -0,0 +1,99 @@
+package migrator
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// LintIssue is a problem with one migration file, Filename is relative to the linted directory.
+type LintIssue struct {
+	Filename string `json:"filename"`
+	Problem  string `json:"problem"`
+}
+
+func (i LintIssue) String() string {
+	return i.Filename + ": " + i.Problem
+}
+
+// Lint checks the migrations of every dialect directory in fsys: each file must parse, have both an up and a
+// down section and a timestamp no other file of the dialect uses. Every dialect must also have a version of
+// each migration. Registered Go migrations must have a down function and a timestamp of their own.
+func Lint(fsys fs.FS) ([]LintIssue, error) {
+	dialects, err := dialectDirs(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []LintIssue
+
+	timestamps := make(map[string][]int, len(dialects))
+
+	for _, dialect := range dialects {
+		filenames, err := fs.Glob(fsys, path.Join(dialect, "*.sql"))
+		if err != nil {
+			return nil, fmt.Errorf("globbing migrations: %w", err)
+		}
+
+		seen := make(map[int]string, len(filenames))
+
+		for _, filename := range filenames {
+			m, err := readMigration(fsys, filename)
+			if err != nil {
+				issues = append(issues, LintIssue{Filename: filename, Problem: err.Error()})
+				continue
+			}
+
+			if strings.TrimSpace(m.Up) == "" {
+				issues = append(issues, LintIssue{Filename: filename, Problem: "up section is empty"})
+			}
+
+			if strings.TrimSpace(m.Down) == "" {
+				issues = append(issues, LintIssue{Filename: filename, Problem: "down section is empty"})
+			}
+
+			if other, ok := seen[m.Timestamp]; ok {
+				issues = append(issues, LintIssue{Filename: filename, Problem: fmt.Sprintf("timestamp %d is also used by %s", m.Timestamp, other)})
+				continue
+			}
+
+			seen[m.Timestamp] = filename
+			timestamps[dialect] = append(timestamps[dialect], m.Timestamp)
+		}
+	}
+
+	for _, m := range registry {
+		if m.DownFunc == nil {
+			issues = append(issues, LintIssue{Filename: m.Filename, Problem: "down function is missing"})
+		}
+
+		for _, dialect := range dialects {
+			if slices.Contains(timestamps[dialect], m.Timestamp) {
+				issues = append(issues, LintIssue{Filename: m.Filename, Problem: fmt.Sprintf("timestamp %d is also used by a %s migration", m.Timestamp, dialect)})
+			}
+		}
+	}
+
+	// a migration one dialect has must exist for all of them, report it once under each dialect lacking it
+	var all []int
+	for _, dialect := range dialects {
+		for _, timestamp := range timestamps[dialect] {
+			if !slices.Contains(all, timestamp) {
+				all = append(all, timestamp)
+			}
+		}
+	}
+	slices.Sort(all)
+
+	for _, dialect := range dialects {
+		for _, timestamp := range all {
+			if !slices.Contains(timestamps[dialect], timestamp) {
+				issues = append(issues, LintIssue{Filename: dialect, Problem: fmt.Sprintf("no migration with timestamp %d, other dialects have one", timestamp)})
+			}
+		}
+	}
+
+	return issues, nil
+}
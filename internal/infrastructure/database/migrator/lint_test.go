@@ -0,0 +1,65 @@
+package migrator
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldLintTheEmbeddedMigrations(t *testing.T) {
+	migrations, err := fs.Sub(migrationsFS, "migrations")
+	require.NoError(t, err)
+
+	issues, err := Lint(migrations)
+	require.NoError(t, err)
+	require.Empty(t, issues)
+}
+
+func TestShouldReportInvalidMigrations(t *testing.T) {
+	valid := &fstest.MapFile{Data: []byte("-- migrate up\ncreate table t (id integer);\n-- migrate down\ndrop table t;\n")}
+
+	issues, err := Lint(fstest.MapFS{
+		"sqlite/1-create-t.sql":        valid,
+		"sqlite/1-create-t-again.sql":  valid,
+		"sqlite/2-no-down.sql":         {Data: []byte("-- migrate up\ncreate table u (id integer);\n")},
+		"sqlite/no-timestamp.sql":      valid,
+		"postgres/1-create-t.sql":      valid,
+		"postgres/3-postgres-only.sql": valid,
+	})
+	require.NoError(t, err)
+
+	var problems []string
+	for _, issue := range issues {
+		problems = append(problems, issue.String())
+	}
+
+	require.ElementsMatch(t, []string{
+		"postgres: no migration with timestamp 2, other dialects have one",
+		"sqlite: no migration with timestamp 3, other dialects have one",
+		"sqlite/1-create-t.sql: timestamp 1 is also used by sqlite/1-create-t-again.sql",
+		"sqlite/2-no-down.sql: down section is empty",
+		`sqlite/no-timestamp.sql: reading migration file sqlite/no-timestamp.sql: strconv.Atoi: parsing "": invalid syntax`,
+	}, problems)
+}
+
+func TestShouldCreateMigrationsAfterTheNewestOne(t *testing.T) {
+	dir := t.TempDir()
+	for _, dialect := range []string{"sqlite", "postgres"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, dialect), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, dialect, "9999999999-later.sql"), []byte(migrationTemplate), 0o644))
+	}
+
+	created, err := Create(dir, "Add events table")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "postgres", "10000000000-add-events-table.sql"),
+		filepath.Join(dir, "sqlite", "10000000000-add-events-table.sql"),
+	}, created)
+
+	_, err = Create(dir, "!!")
+	require.ErrorIs(t, err, ErrInvalidMigrationName)
+}
@@ -0,0 +1,11 @@
+-- migrate up
+CREATE TABLE migrations (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"timestamp" BIGINT NOT NULL,
+	"filename" TEXT NOT NULL,
+    "created_at" BIGINT NOT NULL,
+    "updated_at" BIGINT NOT NULL
+);
+
+-- migrate down
+DROP TABLE migrations;
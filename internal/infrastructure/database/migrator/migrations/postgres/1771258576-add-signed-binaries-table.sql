@@ -0,0 +1,12 @@
+-- migrate up
+CREATE TABLE signed_binaries (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    hash TEXT NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_signed_binaries_hash ON signed_binaries (hash);
+
+-- migrate down
+DROP TABLE signed_binaries;
@@ -0,0 +1,10 @@
+-- migrate up
+ALTER TABLE signed_binaries ADD COLUMN signature TEXT NOT NULL DEFAULT '';
+
+-- Hashes used to be hex encoded with 32 leading zero bytes, strip them so they match a plain SHA-256
+UPDATE signed_binaries
+SET hash = substr(hash, 65)
+WHERE length(hash) = 128 AND substr(hash, 1, 64) = '0000000000000000000000000000000000000000000000000000000000000000';
+
+-- migrate down
+ALTER TABLE signed_binaries DROP COLUMN signature;
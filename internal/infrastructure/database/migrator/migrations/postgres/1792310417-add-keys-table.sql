@@ -0,0 +1,22 @@
+-- migrate up
+CREATE TABLE keys (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    fingerprint TEXT NOT NULL,
+    algorithm TEXT NOT NULL,
+    public_key TEXT NOT NULL,
+    private_key TEXT NOT NULL,
+    status TEXT NOT NULL,
+    activated_at BIGINT NOT NULL DEFAULT 0,
+    retired_at BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_keys_fingerprint ON keys (fingerprint);
+
+ALTER TABLE signed_binaries ADD COLUMN key_id BIGINT REFERENCES keys (id);
+
+-- migrate down
+ALTER TABLE signed_binaries DROP COLUMN key_id;
+
+DROP TABLE keys;
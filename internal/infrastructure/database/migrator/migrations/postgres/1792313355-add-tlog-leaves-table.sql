@@ -0,0 +1,33 @@
+-- migrate up
+CREATE TABLE tlog_leaves (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    leaf_index BIGINT NOT NULL,
+    hash TEXT NOT NULL,
+    key_id TEXT NOT NULL,
+    "timestamp" BIGINT NOT NULL,
+    leaf_hash TEXT NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_tlog_leaves_leaf_index ON tlog_leaves (leaf_index);
+
+CREATE INDEX idx_tlog_leaves_hash ON tlog_leaves (hash);
+
+-- The log is append-only, refuse to rewrite history even from a SQL shell
+CREATE FUNCTION tlog_leaves_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'tlog_leaves is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tlog_leaves_no_update BEFORE UPDATE ON tlog_leaves
+FOR EACH ROW EXECUTE FUNCTION tlog_leaves_append_only();
+
+CREATE TRIGGER tlog_leaves_no_delete BEFORE DELETE ON tlog_leaves
+FOR EACH ROW EXECUTE FUNCTION tlog_leaves_append_only();
+
+-- migrate down
+DROP TABLE tlog_leaves;
+
+DROP FUNCTION tlog_leaves_append_only();
@@ -0,0 +1,28 @@
+-- migrate up
+CREATE TABLE events (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    kind TEXT NOT NULL,
+    hash TEXT NOT NULL DEFAULT '',
+    outcome TEXT NOT NULL,
+    status_code INTEGER NOT NULL,
+    request_id TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL DEFAULT '',
+    country_code TEXT NOT NULL DEFAULT '',
+    country_name TEXT NOT NULL DEFAULT '',
+    time_zone TEXT NOT NULL DEFAULT '',
+    is_desktop BOOLEAN,
+    is_mobile BOOLEAN,
+    is_tablet BOOLEAN,
+    user_agent TEXT NOT NULL DEFAULT '',
+    latency_us BIGINT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX idx_events_created_at ON events (created_at, id);
+
+CREATE INDEX idx_events_hash ON events (hash);
+
+CREATE INDEX idx_events_kind_created_at ON events (kind, created_at);
+
+-- migrate down
+DROP TABLE events;
@@ -0,0 +1,30 @@
+-- migrate up
+CREATE TABLE event_rollups (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    granularity TEXT NOT NULL,
+    bucket_start BIGINT NOT NULL,
+    kind TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    outcome TEXT NOT NULL,
+    country_code TEXT NOT NULL,
+    device TEXT NOT NULL,
+    total BIGINT NOT NULL,
+    total_latency_us BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_event_rollups_bucket ON event_rollups (granularity, bucket_start, kind, hash, outcome, country_code, device);
+
+CREATE TABLE rollup_watermarks (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    last_event_id BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+-- migrate down
+DROP TABLE rollup_watermarks;
+
+DROP TABLE event_rollups;
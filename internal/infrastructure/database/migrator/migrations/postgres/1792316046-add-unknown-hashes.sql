@@ -0,0 +1,46 @@
+-- migrate up
+CREATE TABLE unknown_hashes (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    hash TEXT NOT NULL UNIQUE,
+    total BIGINT NOT NULL,
+    first_seen_at BIGINT NOT NULL,
+    last_seen_at BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE INDEX idx_unknown_hashes_total ON unknown_hashes (total);
+
+CREATE INDEX idx_unknown_hashes_last_seen_at ON unknown_hashes (last_seen_at);
+
+CREATE TABLE unknown_hash_countries (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    hash TEXT NOT NULL,
+    country_code TEXT NOT NULL,
+    total BIGINT NOT NULL,
+    first_seen_at BIGINT NOT NULL,
+    last_seen_at BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_unknown_hash_countries_hash ON unknown_hash_countries (hash, country_code);
+
+CREATE INDEX idx_unknown_hash_countries_country_code ON unknown_hash_countries (country_code);
+
+INSERT INTO unknown_hashes (hash, total, first_seen_at, last_seen_at, created_at, updated_at)
+SELECT hash, count(*), min(created_at), max(created_at), max(created_at), max(created_at)
+FROM events
+WHERE kind = 'checksign' AND outcome = 'unsigned' AND hash <> ''
+GROUP BY hash;
+
+INSERT INTO unknown_hash_countries (hash, country_code, total, first_seen_at, last_seen_at, created_at, updated_at)
+SELECT hash, country_code, count(*), min(created_at), max(created_at), max(created_at), max(created_at)
+FROM events
+WHERE kind = 'checksign' AND outcome = 'unsigned' AND hash <> ''
+GROUP BY hash, country_code;
+
+-- migrate down
+DROP TABLE unknown_hash_countries;
+
+DROP TABLE unknown_hashes;
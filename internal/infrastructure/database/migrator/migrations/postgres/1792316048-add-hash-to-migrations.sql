@@ -0,0 +1,5 @@
+-- migrate up
+ALTER TABLE migrations ADD COLUMN hash TEXT NOT NULL DEFAULT '';
+
+-- migrate down
+ALTER TABLE migrations DROP COLUMN hash;
@@ -0,0 +1,22 @@
+-- migrate up
+CREATE TABLE keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    fingerprint TEXT NOT NULL,
+    algorithm TEXT NOT NULL,
+    public_key TEXT NOT NULL,
+    private_key TEXT NOT NULL,
+    status TEXT NOT NULL,
+    activated_at INTEGER NOT NULL DEFAULT 0,
+    retired_at INTEGER NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_keys_fingerprint ON keys (fingerprint);
+
+ALTER TABLE signed_binaries ADD COLUMN key_id INTEGER REFERENCES keys (id);
+
+-- migrate down
+ALTER TABLE signed_binaries DROP COLUMN key_id;
+
+DROP TABLE keys;
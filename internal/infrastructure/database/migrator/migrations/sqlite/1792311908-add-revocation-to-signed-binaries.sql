@@ -0,0 +1,13 @@
+-- migrate up
+ALTER TABLE signed_binaries ADD COLUMN revoked_at INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE signed_binaries ADD COLUMN revocation_reason TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_signed_binaries_revoked_at ON signed_binaries (revoked_at);
+
+-- migrate down
+DROP INDEX idx_signed_binaries_revoked_at;
+
+ALTER TABLE signed_binaries DROP COLUMN revocation_reason;
+
+ALTER TABLE signed_binaries DROP COLUMN revoked_at;
@@ -0,0 +1,29 @@
+-- migrate up
+CREATE TABLE tlog_leaves (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    leaf_index INTEGER NOT NULL,
+    hash TEXT NOT NULL,
+    key_id TEXT NOT NULL,
+    "timestamp" INTEGER NOT NULL,
+    leaf_hash TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_tlog_leaves_leaf_index ON tlog_leaves (leaf_index);
+
+CREATE INDEX idx_tlog_leaves_hash ON tlog_leaves (hash);
+
+-- The log is append-only, refuse to rewrite history even from a SQL shell
+CREATE TRIGGER tlog_leaves_no_update BEFORE UPDATE ON tlog_leaves
+BEGIN
+    SELECT RAISE(ABORT, 'tlog_leaves is append-only');
+END;
+
+CREATE TRIGGER tlog_leaves_no_delete BEFORE DELETE ON tlog_leaves
+BEGIN
+    SELECT RAISE(ABORT, 'tlog_leaves is append-only');
+END;
+
+-- migrate down
+DROP TABLE tlog_leaves;
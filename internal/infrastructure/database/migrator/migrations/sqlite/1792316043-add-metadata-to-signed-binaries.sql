@@ -0,0 +1,37 @@
+-- migrate up
+ALTER TABLE signed_binaries ADD COLUMN artifact_name TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE signed_binaries ADD COLUMN version TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE signed_binaries ADD COLUMN os TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE signed_binaries ADD COLUMN arch TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE signed_binaries ADD COLUMN filename TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE signed_binaries ADD COLUMN size INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE signed_binaries ADD COLUMN uploader TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE signed_binaries ADD COLUMN labels TEXT NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_signed_binaries_artifact ON signed_binaries (artifact_name, version);
+
+-- migrate down
+DROP INDEX idx_signed_binaries_artifact;
+
+ALTER TABLE signed_binaries DROP COLUMN labels;
+
+ALTER TABLE signed_binaries DROP COLUMN uploader;
+
+ALTER TABLE signed_binaries DROP COLUMN size;
+
+ALTER TABLE signed_binaries DROP COLUMN filename;
+
+ALTER TABLE signed_binaries DROP COLUMN arch;
+
+ALTER TABLE signed_binaries DROP COLUMN os;
+
+ALTER TABLE signed_binaries DROP COLUMN version;
+
+ALTER TABLE signed_binaries DROP COLUMN artifact_name;
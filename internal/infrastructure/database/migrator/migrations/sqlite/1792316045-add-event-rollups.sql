@@ -0,0 +1,30 @@
+-- migrate up
+CREATE TABLE event_rollups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    granularity TEXT NOT NULL,
+    bucket_start INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    outcome TEXT NOT NULL,
+    country_code TEXT NOT NULL,
+    device TEXT NOT NULL,
+    total INTEGER NOT NULL,
+    total_latency_us INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_event_rollups_bucket ON event_rollups (granularity, bucket_start, kind, hash, outcome, country_code, device);
+
+CREATE TABLE rollup_watermarks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    last_event_id INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+-- migrate down
+DROP TABLE rollup_watermarks;
+
+DROP TABLE event_rollups;
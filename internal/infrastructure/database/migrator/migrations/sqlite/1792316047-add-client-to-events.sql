@@ -0,0 +1,29 @@
+-- migrate up
+ALTER TABLE events ADD COLUMN client_kind TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE events ADD COLUMN client_name TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE events ADD COLUMN client_version TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE events ADD COLUMN client_os TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE events ADD COLUMN client_device TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE events ADD COLUMN client_command TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE events ADD COLUMN client_ci TEXT NOT NULL DEFAULT '';
+
+-- migrate down
+ALTER TABLE events DROP COLUMN client_ci;
+
+ALTER TABLE events DROP COLUMN client_command;
+
+ALTER TABLE events DROP COLUMN client_device;
+
+ALTER TABLE events DROP COLUMN client_os;
+
+ALTER TABLE events DROP COLUMN client_version;
+
+ALTER TABLE events DROP COLUMN client_name;
+
+ALTER TABLE events DROP COLUMN client_kind;
@@ -4,10 +4,11 @@ import (
 	"bytes"
 	"context"
 	"embed"
+	"errors"
 	"fmt"
-	"io"
 	"io/fs"
 	"log/slog"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -27,66 +28,88 @@ var (
 	timestampReg, _ = regexp.Compile(`^\d+`)
 )
 
+// addHashToMigrationsTimestamp is the migration adding the hash column to the migrations table, see
+// history.isBootstrap.
+const addHashToMigrationsTimestamp = 1792316048
+
+var (
+	ErrOutOfOrderMigration = errors.New("pending migrations are older than the last applied one, rerun with out of order migrations allowed to apply them")
+)
+
 type Migration struct {
 	Up        string
 	Down      string
 	Filename  string
 	Timestamp int
+	// Hash is the checksum of Up, it is recorded when the migration is applied
+	Hash string
+	// UpFunc and DownFunc are set instead of Up and Down for migrations written in Go
+	UpFunc   MigrationFunc
+	DownFunc MigrationFunc
+}
+
+type upOptions struct {
+	allowOutOfOrder bool
 }
 
-func MigrateUp(ctx context.Context) error {
-	changes, err := parseMigrations()
+type UpOption func(*upOptions)
+
+// WithAllowOutOfOrder lets MigrateUp apply pending migrations older than the newest applied one, as happens when
+// branches with new migrations are merged in a different order than they were written.
+func WithAllowOutOfOrder(allow bool) UpOption {
+	return func(o *upOptions) {
+		o.allowOutOfOrder = allow
+	}
+}
+
+// MigrateUp applies every migration of the codebase that hasn't been applied yet, in timestamp order.
+// Applied migrations that aren't part of the codebase are reported and left alone.
+func MigrateUp(ctx context.Context, opts ...UpOption) error {
+	var o upOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	h, err := loadHistory(ctx)
 	if err != nil {
 		return err
 	}
 
-	latestMigrations, err := migrations.GetLatestAppliedMigrations(ctx)
-	if err != nil {
+	if err := h.checkDrift(); err != nil {
 		return err
 	}
 
-	if len(latestMigrations) == len(changes) {
-		slog.InfoContext(ctx, "All migrations have already been applied")
-		return nil
+	h, err = bootstrap(ctx)
+	if err != nil {
+		return err
 	}
 
-	if len(latestMigrations) > len(changes) {
-		return fmt.Errorf("more migrations have been applied than exist in the codebase, this should never happen")
+	for _, unknown := range h.unknown() {
+		slog.WarnContext(ctx, "Applied migration is not part of this build", "filename", unknown.Filename, "timestamp", unknown.Timestamp)
 	}
 
-	if len(latestMigrations) == 0 {
-		slog.InfoContext(ctx, "No migrations have been applied yet, applying all migrations")
-		if err := applyMigrations(ctx, changes); err != nil {
-			return fmt.Errorf("applying migrations: %w", err)
-		}
+	pending := h.pending()
+	if len(pending) == 0 {
+		slog.InfoContext(ctx, "All migrations have already been applied")
 		return nil
 	}
 
-	// Now we need to find the last common migration and apply the rest...
-	lastCommonIndex := -1
-	for i, change := range changes {
-		if i >= len(latestMigrations) {
-			break
-		}
-
-		appliedMigration := latestMigrations[i]
-
-		if change.Timestamp != int(appliedMigration.Timestamp) {
-			break
+	var outOfOrder []string
+	for _, migration := range pending {
+		if migration.Timestamp < h.lastApplied() {
+			outOfOrder = append(outOfOrder, migration.Filename)
 		}
-
-		lastCommonIndex = i
 	}
 
-	if lastCommonIndex == -1 {
-		return fmt.Errorf("no common migration found, this should never happen")
+	if len(outOfOrder) > 0 && !o.allowOutOfOrder {
+		return fmt.Errorf("%w: %s", ErrOutOfOrderMigration, strings.Join(outOfOrder, ", "))
 	}
 
-	if lastCommonIndex == len(changes)-1 {
-		slog.InfoContext(ctx, "All migrations have already been applied")
+	for _, filename := range outOfOrder {
+		slog.WarnContext(ctx, "Applying migration out of order", "filename", filename, "last_applied", h.lastApplied())
 	}
 
-	if err := applyMigrations(ctx, changes[lastCommonIndex+1:]); err != nil {
+	if err := applyMigrations(ctx, pending); err != nil {
 		return fmt.Errorf("applying migrations: %w", err)
 	}
 
@@ -94,60 +117,145 @@ func MigrateUp(ctx context.Context) error {
 }
 
 func applyMigrations(ctx context.Context, migrationList []*Migration) error {
-	err := database.WithinTransaction(ctx, func(ctx context.Context) error {
-		for _, migration := range migrationList {
-			slog.InfoContext(ctx, "applying migration", "filename", migration.Filename)
+	steps := make([]step, 0, len(migrationList))
+	for _, migration := range migrationList {
+		steps = append(steps, step{migration: migration, direction: directionUp})
+	}
 
-			resultSet, err := database.ExecContext(ctx, migration.Up)
-			if err != nil {
-				return fmt.Errorf("applying migration %s: %w", migration.Filename, err)
-			}
+	if err := runSteps(ctx, steps); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Successfully applied all migrations")
+
+	return nil
+}
 
-			rowsAffected, err := resultSet.RowsAffected()
-			if err != nil {
-				return fmt.Errorf("getting rows affected for migration %s: %w", migration.Filename, err)
+type direction int
+
+const (
+	directionUp direction = iota
+	directionDown
+)
+
+// step is a migration to apply or roll back, applied is the record of a migration being rolled back.
+type step struct {
+	migration *Migration
+	direction direction
+	applied   *migrations.Migration
+}
+
+// runSteps runs every step in a single transaction, either all of them take effect or none does. The migrations
+// applied are recorded once every step ran, the bootstrap batch creates the migrations table before adding the
+// hash column its records need.
+func runSteps(ctx context.Context, steps []step) error {
+	// a running api may be holding the lock, the whole batch is retried as it is a single transaction
+	return database.RetryOnBusy(ctx, func(ctx context.Context) error {
+		return database.WithinTransaction(ctx, func(ctx context.Context) error {
+			var applied []*Migration
+
+			for _, s := range steps {
+				var err error
+				switch s.direction {
+				case directionUp:
+					err = runUp(ctx, s.migration)
+					applied = append(applied, s.migration)
+				case directionDown:
+					err = runDown(ctx, s.migration, s.applied)
+				}
+				if err != nil {
+					return err
+				}
 			}
 
-			m := migrations.NewMigration(
-				migrations.WithFilename(migration.Filename),
-				migrations.WithTimestamp(int64(migration.Timestamp)),
-			)
-			if _, err := migrations.Create(ctx, m); err != nil {
-				return fmt.Errorf("inserting migration record for %s: %w", migration.Filename, err)
+			for _, migration := range applied {
+				if err := recordApplied(ctx, migration); err != nil {
+					return fmt.Errorf("inserting migration record for %s: %w", migration.Filename, err)
+				}
 			}
 
-			slog.InfoContext(ctx, "applied migration", "filename", migration.Filename, "rowsAffected", rowsAffected)
-		}
-		return nil
+			return nil
+		})
 	})
+}
+
+func runUp(ctx context.Context, migration *Migration) error {
+	slog.InfoContext(ctx, "applying migration", "filename", migration.Filename)
+
+	var rowsAffected int64
+	if migration.UpFunc != nil {
+		if err := migration.UpFunc(ctx); err != nil {
+			return fmt.Errorf("applying migration %s: %w", migration.Filename, err)
+		}
+	} else {
+		resultSet, err := database.ExecContext(ctx, migration.Up)
+		if err != nil {
+			return fmt.Errorf("applying migration %s: %w", migration.Filename, err)
+		}
+
+		rowsAffected, err = resultSet.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("getting rows affected for migration %s: %w", migration.Filename, err)
+		}
+	}
+
+	slog.InfoContext(ctx, "applied migration", "filename", migration.Filename, "rowsAffected", rowsAffected)
+
+	return nil
+}
+
+// recordApplied inserts the record of an applied migration, along with its checksum.
+func recordApplied(ctx context.Context, migration *Migration) error {
+	_, err := migrations.Create(ctx, migrations.NewMigration(
+		migrations.WithFilename(migration.Filename),
+		migrations.WithTimestamp(int64(migration.Timestamp)),
+		migrations.WithHash(migration.Hash),
+	))
+
+	return err
+}
+
+func runDown(ctx context.Context, migration *Migration, applied *migrations.Migration) error {
+	slog.InfoContext(ctx, "rolling back migration", "filename", migration.Filename)
+
+	var err error
+	if migration.DownFunc != nil {
+		err = migration.DownFunc(ctx)
+	} else {
+		_, err = database.ExecContext(ctx, migration.Down)
+	}
 	if err != nil {
-		return err
+		return fmt.Errorf("rolling back migration %s: %w", migration.Filename, err)
 	}
 
-	slog.InfoContext(ctx, "Successfully applied all migrations")
+	if err := migrations.Delete(ctx, applied); err != nil {
+		return fmt.Errorf("deleting migration record for %s: %w", migration.Filename, err)
+	}
+
+	slog.InfoContext(ctx, "rolled back migration", "filename", migration.Filename)
 
 	return nil
 }
 
+// AreWeAtTheLatestMigration is true when every migration of the codebase has been applied. It fails with
+// ErrMigrationDrift when an applied migration has been edited since.
 func AreWeAtTheLatestMigration(ctx context.Context) (bool, error) {
-	changes, err := parseMigrations()
+	h, err := loadHistory(ctx)
 	if err != nil {
 		return false, err
 	}
 
-	lastMigration, err := migrations.GetLastMigration(ctx)
-	if err != nil {
+	if err := h.checkDrift(); err != nil {
 		return false, err
 	}
-	if lastMigration == nil {
-		return len(changes) == 0, nil
-	}
 
-	return changes[len(changes)-1].Timestamp == int(lastMigration.Timestamp), nil
+	return len(h.pending()) == 0, nil
 }
 
+// parseMigrations reads the migrations of the dialect in use, each dialect has its own directory under migrations.
+// Registered Go migrations are interleaved with them.
 func parseMigrations() ([]*Migration, error) {
-	filenames, err := fs.Glob(migrationsFS, "*/*.sql")
+	filenames, err := fs.Glob(migrationsFS, path.Join("migrations", database.CurrentDialect().Name(), "*.sql"))
 	if err != nil {
 		return nil, fmt.Errorf("globbing migrations: %w", err)
 	}
@@ -155,33 +263,24 @@ func parseMigrations() ([]*Migration, error) {
 	changes := make([]*Migration, 0, len(filenames))
 
 	for _, filename := range filenames {
-		readFile := func() error {
-			file, err := migrationsFS.Open(filename)
-			if err != nil {
-				return err
-			}
-			defer file.Close()
+		m, err := readMigration(migrationsFS, filename)
+		if err != nil {
+			return nil, err
+		}
 
-			data, err := io.ReadAll(file)
-			if err != nil {
-				return err
-			}
+		changes = append(changes, m)
+	}
 
-			m, err := parseMigration(data, filename)
-			if err != nil {
-				return err
+	for _, m := range registry {
+		for _, change := range changes {
+			if change.Timestamp == m.Timestamp {
+				return nil, fmt.Errorf("%s and %s share timestamp %d", m.Filename, change.Filename, m.Timestamp)
 			}
-
-			changes = append(changes, m)
-
-			return nil
-		}
-
-		if err := readFile(); err != nil {
-			return nil, fmt.Errorf("reading migration file %s: %w", filename, err)
 		}
 	}
 
+	changes = append(changes, registry...)
+
 	// Just to be sure
 	sort.Slice(changes, func(i, j int) bool {
 		return changes[i].Timestamp < changes[j].Timestamp
@@ -190,6 +289,20 @@ func parseMigrations() ([]*Migration, error) {
 	return changes, nil
 }
 
+func readMigration(fsys fs.FS, filename string) (*Migration, error) {
+	data, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading migration file %s: %w", filename, err)
+	}
+
+	m, err := parseMigration(data, filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading migration file %s: %w", filename, err)
+	}
+
+	return m, nil
+}
+
 func parseMigration(migrationStr []byte, filename string) (*Migration, error) {
 	m := &Migration{
 		Filename: filename,
@@ -204,6 +317,7 @@ func parseMigration(migrationStr []byte, filename string) (*Migration, error) {
 	}
 
 	m.Up, m.Down = parseChange(migrationStr)
+	m.Hash = checksum(m.Up)
 
 	return m, nil
 }
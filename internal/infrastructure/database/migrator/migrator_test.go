@@ -9,19 +9,18 @@ import (
 )
 
 var (
-	//go:embed migrations/1771180375-add-migrations-table.sql
+	//go:embed migrations/sqlite/1771180375-add-migrations-table.sql
 	addMigrationsMigration string
 )
 
 func TestShouldBeAbleToParseMigrateUpFile(t *testing.T) {
-	migration, err := parseMigration([]byte(addMigrationsMigration), "migrations/1771180375-add-migrations-table.sql")
+	migration, err := parseMigration([]byte(addMigrationsMigration), "migrations/sqlite/1771180375-add-migrations-table.sql")
 	require.NoError(t, err)
 
 	migrateUp := `CREATE TABLE migrations (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
 	"timestamp" INTEGER NOT NULL,
 	"filename" TEXT NOT NULL,
-    "hash" TEXT NOT NULL,
     "created_at" INTEGER NOT NULL,
     "updated_at" INTEGER NOT NULL
 );`
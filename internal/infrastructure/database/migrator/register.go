@@ -0,0 +1,50 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MigrationFunc runs within the migration transaction, every database helper called with ctx takes part in it.
+type MigrationFunc func(ctx context.Context) error
+
+// registry holds the Go migrations, they are applied alongside the SQL ones in timestamp order.
+var registry []*Migration
+
+// Register adds a migration written in Go, for changes plain SQL can't express. It is meant to be called from
+// an init function in this package, in a file named after the migration. Down may be nil, such migrations
+// can't be rolled back and are reported by Lint.
+//
+// Go migrations run against every dialect and are recorded like SQL ones, under a go/ filename. Their code
+// can't be checksummed, the filename is used instead so renaming one counts as drift.
+func Register(timestamp int, name string, up, down MigrationFunc) {
+	if up == nil {
+		panic(fmt.Sprintf("migrator.Register: migration %d-%s has no up function", timestamp, name))
+	}
+
+	for _, m := range registry {
+		if m.Timestamp == timestamp {
+			panic(fmt.Sprintf("migrator.Register: timestamp %d is already used by %s", timestamp, m.Filename))
+		}
+	}
+
+	filename := fmt.Sprintf("go/%d-%s", timestamp, name)
+
+	registry = append(registry, &Migration{
+		Filename:  filename,
+		Timestamp: timestamp,
+		Hash:      checksum(filename),
+		UpFunc:    up,
+		DownFunc:  down,
+	})
+}
+
+// reversible is true when the migration has a down section or function.
+func (m *Migration) reversible() bool {
+	if m.UpFunc != nil {
+		return m.DownFunc != nil
+	}
+
+	return strings.TrimSpace(m.Down) != ""
+}
@@ -0,0 +1,106 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/stretchr/testify/require"
+)
+
+// withRegistry keeps migrations registered by a test from leaking into the others.
+func withRegistry(t *testing.T) {
+	saved := registry
+	registry = nil
+
+	t.Cleanup(func() {
+		registry = saved
+	})
+}
+
+func TestShouldApplyGoMigrationsBetweenSQLOnes(t *testing.T) {
+	ctx := context.Background()
+	setupTestDB(t)
+	withRegistry(t)
+
+	sqlChanges, err := parseMigrations()
+	require.NoError(t, err)
+
+	// runs after signed_binaries is created, relying on the SQL migrations before it
+	timestamp := sqlChanges[1].Timestamp + 1
+	Register(timestamp, "backfill-signed-binaries", func(ctx context.Context) error {
+		_, err := database.ExecContext(ctx, "insert into signed_binaries (hash, created_at, updated_at) values (?, ?, ?);", "abc", 1, 1)
+		return err
+	}, func(ctx context.Context) error {
+		_, err := database.ExecContext(ctx, "delete from signed_binaries where hash = ?;", "abc")
+		return err
+	})
+
+	changes, err := parseMigrations()
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("go/%d-backfill-signed-binaries", timestamp), changes[2].Filename)
+
+	require.NoError(t, MigrateTo(ctx, timestamp))
+
+	statuses, err := Status(ctx)
+	require.NoError(t, err)
+	require.True(t, statuses[2].Applied)
+	require.Equal(t, changes[2].Filename, statuses[2].Filename)
+
+	// the hash column of the migrations table is added first, every migration has its checksum recorded
+	drifts, err := Verify(ctx)
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+
+	require.NoError(t, MigrateDown(ctx, 1))
+
+	type count struct {
+		Count int `sql:"count"`
+	}
+	rows, err := database.SelectContext[count](ctx, "select count(*) as count from signed_binaries;")
+	require.NoError(t, err)
+	require.Equal(t, 0, rows[0].Count)
+}
+
+func TestShouldRollBackTheBatchWhenAGoMigrationFails(t *testing.T) {
+	ctx := context.Background()
+	setupTestDB(t)
+	withRegistry(t)
+
+	failure := errors.New("cannot rehash")
+	Register(1771258577, "rehash", func(ctx context.Context) error {
+		return failure
+	}, nil)
+
+	require.ErrorIs(t, MigrateUp(ctx), failure)
+
+	// only the migrations of the migrations table, applied ahead of the batch, remain
+	statuses, err := Status(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		require.Equal(t, s.Timestamp == 1771180375 || s.Timestamp == addHashToMigrationsTimestamp, s.Applied, s.Filename)
+	}
+}
+
+func TestShouldReportGoMigrationsWithoutDown(t *testing.T) {
+	ctx := context.Background()
+	setupTestDB(t)
+	withRegistry(t)
+
+	Register(9999999999, "irreversible", func(ctx context.Context) error {
+		return nil
+	}, nil)
+
+	require.NoError(t, MigrateUp(ctx))
+	require.ErrorIs(t, MigrateDown(ctx, 1), ErrIrreversibleMigration)
+
+	migrations, err := fs.Sub(migrationsFS, "migrations")
+	require.NoError(t, err)
+
+	issues, err := Lint(migrations)
+	require.NoError(t, err)
+	require.Contains(t, issues, LintIssue{Filename: "go/9999999999-irreversible", Problem: "down function is missing"})
+}
@@ -0,0 +1,43 @@
+package migrator
+
+import (
+	"context"
+	"slices"
+	"sort"
+)
+
+type MigrationStatus struct {
+	Filename  string `json:"filename"`
+	Timestamp int    `json:"timestamp"`
+	Applied   bool   `json:"applied"`
+	// AppliedAt is in unix nanoseconds, zero while the migration is pending
+	AppliedAt int64 `json:"applied_at,omitempty"`
+}
+
+// Status lists every migration in the codebase, along with applied ones whose file is gone, oldest first.
+func Status(ctx context.Context) ([]MigrationStatus, error) {
+	h, err := loadHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(h.changes))
+	for _, change := range h.changes {
+		statuses = append(statuses, MigrationStatus{Filename: change.Filename, Timestamp: change.Timestamp})
+	}
+
+	for _, applied := range h.applied {
+		i := sort.Search(len(statuses), func(i int) bool {
+			return statuses[i].Timestamp >= int(applied.Timestamp)
+		})
+
+		if i == len(statuses) || statuses[i].Timestamp != int(applied.Timestamp) {
+			statuses = slices.Insert(statuses, i, MigrationStatus{Filename: applied.Filename, Timestamp: int(applied.Timestamp)})
+		}
+
+		statuses[i].Applied = true
+		statuses[i].AppliedAt = applied.CreatedAt
+	}
+
+	return statuses, nil
+}
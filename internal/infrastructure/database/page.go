@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+var (
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrInvalidSortOrder = errors.New("invalid sort order, expected asc or desc")
+)
+
+// Pageable rows are ordered by (created_at, id), which is unique and stable even when timestamps collide.
+type Pageable interface {
+	GetID() int
+	GetCreatedAt() int64
+}
+
+// PageQuery describes a keyset paginated select. Query must select the created_at and id columns
+// and must not contain where, order by nor limit clauses, those are built from the other fields.
+type PageQuery struct {
+	Query  string
+	Where  []string
+	Args   []any
+	Cursor string
+	Limit  int
+	Order  SortOrder
+}
+
+type Page[T any] struct {
+	Items      []*T
+	NextCursor string
+}
+
+func ParseSortOrder(order string) (SortOrder, error) {
+	switch SortOrder(strings.ToLower(order)) {
+	case "", SortDescending:
+		return SortDescending, nil
+	case SortAscending:
+		return SortAscending, nil
+	default:
+		return "", ErrInvalidSortOrder
+	}
+}
+
+// ClampLimit is the limit callers asked for, defaultLimit when they didn't ask for any and never more than maxLimit.
+func ClampLimit(limit, defaultLimit, maxLimit int) int {
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+
+	return min(limit, maxLimit)
+}
+
+func encodeCursor(createdAt int64, id int) string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%d", createdAt, id))
+}
+
+func decodeCursor(cursor string) (int64, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+
+	createdAtString, idString, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return 0, 0, ErrInvalidCursor
+	}
+
+	createdAt, err := strconv.ParseInt(createdAtString, 10, 64)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.Atoi(idString)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+
+	return createdAt, id, nil
+}
+
+// SelectPageContext runs q and returns at most q.Limit rows after q.Cursor, along with the cursor of the next page
+// (empty when there is none).
+func SelectPageContext[T any, PT interface {
+	*T
+	Pageable
+}](ctx context.Context, q PageQuery) (*Page[T], error) {
+	if q.Limit <= 0 {
+		return nil, fmt.Errorf("page limit must be positive, got %d", q.Limit)
+	}
+
+	if q.Order == "" {
+		q.Order = SortDescending
+	}
+
+	comparison := "<"
+	if q.Order == SortAscending {
+		comparison = ">"
+	} else if q.Order != SortDescending {
+		return nil, ErrInvalidSortOrder
+	}
+
+	where := append([]string{}, q.Where...)
+	args := append([]any{}, q.Args...)
+
+	if q.Cursor != "" {
+		createdAt, id, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		where = append(where, fmt.Sprintf("(created_at %[1]s ? or (created_at = ? and id %[1]s ?))", comparison))
+		args = append(args, createdAt, createdAt, id)
+	}
+
+	var query strings.Builder
+	query.WriteString(q.Query)
+
+	if len(where) > 0 {
+		query.WriteString(" where ")
+		query.WriteString(strings.Join(where, " and "))
+	}
+
+	fmt.Fprintf(&query, " order by created_at %[1]s, id %[1]s limit ?;", q.Order)
+
+	// One extra row tells us whether there is a next page without a count query
+	args = append(args, q.Limit+1)
+
+	items, err := SelectContext[T](ctx, query.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page[T]{Items: items}
+
+	if len(items) > q.Limit {
+		page.Items = items[:q.Limit]
+
+		var last PT = page.Items[q.Limit-1]
+		page.NextCursor = encodeCursor(last.GetCreatedAt(), last.GetID())
+	}
+
+	return page, nil
+}
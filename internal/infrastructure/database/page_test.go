@@ -0,0 +1,27 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldClampLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		expected int
+	}{
+		{name: "not given", limit: 0, expected: 20},
+		{name: "negative", limit: -1, expected: 20},
+		{name: "within range", limit: 50, expected: 50},
+		{name: "the maximum", limit: 100, expected: 100},
+		{name: "above the maximum", limit: 101, expected: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, ClampLimit(tt.limit, 20, 100))
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	createdAtColumn = "created_at"
+	updatedAtColumn = "updated_at"
+)
+
+var (
+	ErrRecordNotFound        = errors.New("record not found")
+	ErrUnknownConflictColumn = errors.New("conflict column is not part of the table")
+)
+
+// Repository generates the basic statements for a table out of the sql struct tags of T, columns are
+// taken in struct field order and the id column is always left to the database.
+// created_at and updated_at, when T has them, are kept up to date as unix nanoseconds.
+type Repository[T any, PT interface {
+	*T
+	HasID
+}] struct {
+	table   string
+	columns []string
+}
+
+func NewRepository[T any, PT interface {
+	*T
+	HasID
+}](table string) *Repository[T, PT] {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("database.NewRepository: expected struct, got %s", t.Kind()))
+	}
+
+	var columns []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || isIDField(field) {
+			continue
+		}
+
+		columns = append(columns, columnName(field))
+	}
+
+	return &Repository[T, PT]{table: table, columns: columns}
+}
+
+func isIDField(field reflect.StructField) bool {
+	return field.Name == "ID" || field.Tag.Get("sql") == "id"
+}
+
+// GetByID returns nil when there is no row with the given id.
+func (r *Repository[T, PT]) GetByID(ctx context.Context, id int) (PT, error) {
+	rows, err := SelectContext[T](ctx, fmt.Sprintf("select * from %s where id = ?;", r.table), id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	return rows[0], nil
+}
+
+// Insert stores obj and sets its id, zero created_at and updated_at are set to now.
+func (r *Repository[T, PT]) Insert(ctx context.Context, obj PT) error {
+	now := time.Now().UnixNano()
+	setTimestamp(obj, createdAtColumn, now, false)
+	setTimestamp(obj, updatedAtColumn, now, false)
+
+	return InsertContext(ctx, r.insertQuery(), obj)
+}
+
+// Update overwrites every column of the row with obj's id and bumps updated_at.
+func (r *Repository[T, PT]) Update(ctx context.Context, obj PT) error {
+	setTimestamp(obj, updatedAtColumn, time.Now().UnixNano(), true)
+
+	assignments := make([]string, 0, len(r.columns))
+	for _, column := range r.columns {
+		assignments = append(assignments, column+" = ?")
+	}
+
+	query := fmt.Sprintf("update %s set %s where id = ?;", r.table, strings.Join(assignments, ", "))
+
+	args, err := extractInsertArgs(obj)
+	if err != nil {
+		return err
+	}
+
+	result, err := ExecContext(ctx, query, append(args, obj.GetID())...)
+	if err != nil {
+		return err
+	}
+
+	return requireAffectedRow(result.RowsAffected())
+}
+
+func (r *Repository[T, PT]) Delete(ctx context.Context, id int) error {
+	result, err := ExecContext(ctx, fmt.Sprintf("delete from %s where id = ?;", r.table), id)
+	if err != nil {
+		return err
+	}
+
+	return requireAffectedRow(result.RowsAffected())
+}
+
+// Upsert inserts obj, or updates the row that already has the same values in conflictColumns, which must be
+// covered by a unique index. The existing created_at is kept. obj's id is set to the id of the row either way.
+func (r *Repository[T, PT]) Upsert(ctx context.Context, obj PT, conflictColumns ...string) error {
+	if len(conflictColumns) == 0 {
+		return fmt.Errorf("upserting into %s: at least one conflict column is required", r.table)
+	}
+
+	for _, column := range conflictColumns {
+		if !slices.Contains(r.columns, column) {
+			return fmt.Errorf("upserting into %s: %q: %w", r.table, column, ErrUnknownConflictColumn)
+		}
+	}
+
+	now := time.Now().UnixNano()
+	setTimestamp(obj, createdAtColumn, now, false)
+	setTimestamp(obj, updatedAtColumn, now, true)
+
+	var assignments []string
+	for _, column := range r.columns {
+		if column == createdAtColumn || slices.Contains(conflictColumns, column) {
+			continue
+		}
+
+		assignments = append(assignments, fmt.Sprintf("%[1]s = excluded.%[1]s", column))
+	}
+
+	if len(assignments) == 0 {
+		// still touch the row so returning has something to return
+		assignments = append(assignments, fmt.Sprintf("%[1]s = excluded.%[1]s", conflictColumns[0]))
+	}
+
+	query := strings.TrimSuffix(r.insertQuery(), ";")
+	query += fmt.Sprintf(" on conflict (%s) do update set %s returning id;", strings.Join(conflictColumns, ", "), strings.Join(assignments, ", "))
+
+	args, err := extractInsertArgs(obj)
+	if err != nil {
+		return err
+	}
+
+	// LastInsertId is not reliable when the conflicting row is updated
+	ids, err := SelectContext[struct {
+		ID int `sql:"id"`
+	}](ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) != 1 {
+		return fmt.Errorf("upserting into %s: expected 1 row back, got %d", r.table, len(ids))
+	}
+
+	obj.SetID(ids[0].ID)
+
+	return nil
+}
+
+func (r *Repository[T, PT]) insertQuery() string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(r.columns)), ", ")
+
+	return fmt.Sprintf("insert into %s (%s) values (%s);", r.table, strings.Join(r.columns, ", "), placeholders)
+}
+
+func requireAffectedRow(rowsAffected int64, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// setTimestamp sets the int64 field mapped to column, only when it is still zero unless overwrite is set.
+func setTimestamp(obj any, column string, value int64, overwrite bool) {
+	v := reflect.ValueOf(obj).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || columnName(field) != column || field.Type.Kind() != reflect.Int64 {
+			continue
+		}
+
+		if overwrite || v.Field(i).Int() == 0 {
+			v.Field(i).SetInt(value)
+		}
+
+		return
+	}
+}
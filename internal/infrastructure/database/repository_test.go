@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testRecord struct {
+	ID        int    `sql:"id"`
+	Name      string `sql:"name"`
+	Value     string `sql:"value"`
+	CreatedAt int64  `sql:"created_at"`
+	UpdatedAt int64  `sql:"updated_at"`
+}
+
+func (r *testRecord) GetID() int   { return r.ID }
+func (r *testRecord) SetID(id int) { r.ID = id }
+
+func setupTestRepository(t *testing.T) *Repository[testRecord, *testRecord] {
+	t.Helper()
+
+	setupTestDB(t)
+
+	_, err := ExecContext(context.Background(), `create table records (
+		id integer primary key autoincrement,
+		name text not null unique,
+		value text not null,
+		created_at integer not null,
+		updated_at integer not null
+	);`)
+	require.NoError(t, err)
+
+	return NewRepository[testRecord]("records")
+}
+
+func TestShouldInsertGetUpdateAndDeleteRecords(t *testing.T) {
+	ctx := context.Background()
+	repository := setupTestRepository(t)
+
+	record := &testRecord{Name: "a", Value: "1"}
+	require.NoError(t, repository.Insert(ctx, record))
+	require.NotZero(t, record.ID)
+	require.NotZero(t, record.CreatedAt)
+	require.Equal(t, record.CreatedAt, record.UpdatedAt)
+
+	got, err := repository.GetByID(ctx, record.ID)
+	require.NoError(t, err)
+	require.Equal(t, record, got)
+
+	record.Value = "2"
+	require.NoError(t, repository.Update(ctx, record))
+	require.Greater(t, record.UpdatedAt, record.CreatedAt)
+
+	got, err = repository.GetByID(ctx, record.ID)
+	require.NoError(t, err)
+	require.Equal(t, "2", got.Value)
+	require.Equal(t, record.UpdatedAt, got.UpdatedAt)
+
+	require.NoError(t, repository.Delete(ctx, record.ID))
+
+	got, err = repository.GetByID(ctx, record.ID)
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	require.ErrorIs(t, repository.Delete(ctx, record.ID), ErrRecordNotFound)
+	require.ErrorIs(t, repository.Update(ctx, record), ErrRecordNotFound)
+}
+
+func TestShouldUpsertOnConflictColumns(t *testing.T) {
+	ctx := context.Background()
+	repository := setupTestRepository(t)
+
+	first := &testRecord{Name: "a", Value: "1"}
+	require.NoError(t, repository.Upsert(ctx, first, "name"))
+	require.NotZero(t, first.ID)
+
+	second := &testRecord{Name: "a", Value: "2"}
+	require.NoError(t, repository.Upsert(ctx, second, "name"))
+	require.Equal(t, first.ID, second.ID)
+
+	got, err := repository.GetByID(ctx, first.ID)
+	require.NoError(t, err)
+	require.Equal(t, "2", got.Value)
+	require.Equal(t, first.CreatedAt, got.CreatedAt)
+
+	require.ErrorIs(t, repository.Upsert(ctx, second, "missing"), ErrUnknownConflictColumn)
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// Settings are the values in effect on a connection, which may differ from the configured ones
+// (an in-memory database can't use wal for instance). The sqlite pragmas are left empty on postgres.
+type Settings struct {
+	Driver        string `json:"driver"`
+	Path          string `json:"path"`
+	JournalMode   string `json:"journal_mode,omitempty"`
+	BusyTimeoutMS int    `json:"busy_timeout_ms,omitempty"`
+	Synchronous   string `json:"synchronous,omitempty"`
+	ForeignKeys   bool   `json:"foreign_keys"`
+
+	MaxOpenConns    int `json:"max_open_conns"`
+	OpenConnections int `json:"open_connections"`
+	InUse           int `json:"in_use"`
+	Idle            int `json:"idle"`
+}
+
+// CurrentSettings reads the settings back from the database along with the pool statistics.
+func CurrentSettings(ctx context.Context) (*Settings, error) {
+	// everything is read on the same connection, sqlite pragmas are applied per connection
+	c, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	settings := &Settings{
+		Driver:      dialect.Name(),
+		Path:        connectedPath,
+		ForeignKeys: true,
+	}
+
+	if err := dialect.ReadSettings(ctx, c, settings); err != nil {
+		return nil, fmt.Errorf("reading database settings: %w", classify(ctx, err))
+	}
+
+	stats := db.Stats()
+	settings.MaxOpenConns = stats.MaxOpenConnections
+	settings.OpenConnections = stats.OpenConnections
+	settings.InUse = stats.InUse
+	settings.Idle = stats.Idle
+
+	return settings, nil
+}
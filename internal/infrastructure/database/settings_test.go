@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldApplyConfiguredPragmas(t *testing.T) {
+	ctx := context.Background()
+
+	cleanup, err := Connect(ctx, config.DatabaseConfig{
+		Path:         filepath.Join(t.TempDir(), "test.db"),
+		JournalMode:  "WAL",
+		BusyTimeout:  1500 * time.Millisecond,
+		Synchronous:  "full",
+		ForeignKeys:  true,
+		MaxOpenConns: 4,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, cleanup()) })
+
+	settings, err := CurrentSettings(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "wal", settings.JournalMode)
+	require.Equal(t, 1500, settings.BusyTimeoutMS)
+	require.Equal(t, "full", settings.Synchronous)
+	require.True(t, settings.ForeignKeys)
+	require.Equal(t, 4, settings.MaxOpenConns)
+}
+
+func TestShouldRejectInvalidDatabaseConfig(t *testing.T) {
+	_, err := SQLite{}.DataSourceName(config.DatabaseConfig{})
+	require.ErrorIs(t, err, ErrDatabasePathRequired)
+
+	_, err = SQLite{}.DataSourceName(config.DatabaseConfig{Path: "app.db", JournalMode: "fast"})
+	require.ErrorIs(t, err, ErrInvalidJournalMode)
+
+	_, err = SQLite{}.DataSourceName(config.DatabaseConfig{Path: "app.db", Synchronous: "sometimes"})
+	require.ErrorIs(t, err, ErrInvalidSynchronous)
+}
@@ -0,0 +1,28 @@
+package keys
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
+
+const (
+	insertKeyQuery = "insert into keys (fingerprint, algorithm, public_key, private_key, status, activated_at, retired_at, created_at, updated_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?);"
+)
+
+var (
+	ErrDuplicateKey = errors.New("a key with the same fingerprint already exists")
+)
+
+func Create(ctx context.Context, k *Key) (*Key, error) {
+	if err := database.InsertContext(ctx, insertKeyQuery, k); err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return nil, ErrDuplicateKey
+		}
+
+		return nil, err
+	}
+
+	return k, nil
+}
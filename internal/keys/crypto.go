@@ -0,0 +1,106 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const (
+	Algorithm = "ed25519"
+)
+
+var (
+	ErrInvalidPrivateKey = errors.New("invalid private key")
+	ErrInvalidPublicKey  = errors.New("invalid public key")
+	ErrInvalidSignature  = errors.New("signature does not match the signed message")
+)
+
+// ParsePrivateKey decodes a base64 encoded Ed25519 key, accepting either the 32 byte seed or the 64 byte private key.
+func ParsePrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decoding private key: %w", ErrInvalidPrivateKey)
+	}
+
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("unexpected private key length %d: %w", len(raw), ErrInvalidPrivateKey)
+	}
+}
+
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, ErrInvalidPublicKey
+	}
+
+	return ed25519.PublicKey(raw), nil
+}
+
+// Fingerprint is the short, stable identifier verifiers use to pick the right public key.
+func Fingerprint(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+func generatePrivateKey() (ed25519.PrivateKey, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return privateKey, nil
+}
+
+func fromPrivateKey(privateKey ed25519.PrivateKey, opts ...KeyOptions) *Key {
+	publicKey, _ := privateKey.Public().(ed25519.PublicKey)
+
+	k := NewKey(opts...)
+	k.Fingerprint = Fingerprint(publicKey)
+	k.PublicKey = base64.StdEncoding.EncodeToString(publicKey)
+	k.PrivateKey = base64.StdEncoding.EncodeToString(privateKey.Seed())
+
+	return k
+}
+
+// Sign produces a detached, base64 encoded signature over message.
+func (k *Key) Sign(message []byte) (string, error) {
+	privateKey, err := ParsePrivateKey(k.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, message)), nil
+}
+
+func (k *Key) Verify(message []byte, signature string) error {
+	return Verify(k.PublicKey, message, signature)
+}
+
+// Verify checks a base64 signature against a base64 public key, it does not need the database.
+func Verify(encodedPublicKey string, message []byte, signature string) error {
+	publicKey, err := ParsePublicKey(encodedPublicKey)
+	if err != nil {
+		return err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if !ed25519.Verify(publicKey, message, raw) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
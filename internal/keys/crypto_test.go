@@ -0,0 +1,68 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldParseSeedsAndFullPrivateKeys(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	require.NoError(t, err)
+
+	parsed, err := ParsePrivateKey(base64.StdEncoding.EncodeToString(privateKey.Seed()))
+	require.NoError(t, err)
+	require.Equal(t, privateKey, parsed)
+
+	parsed, err = ParsePrivateKey(base64.StdEncoding.EncodeToString(privateKey))
+	require.NoError(t, err)
+	require.Equal(t, privateKey, parsed)
+
+	_, err = ParsePrivateKey("not base64")
+	require.ErrorIs(t, err, ErrInvalidPrivateKey)
+
+	_, err = ParsePrivateKey(base64.StdEncoding.EncodeToString([]byte("too short")))
+	require.ErrorIs(t, err, ErrInvalidPrivateKey)
+}
+
+func TestShouldVerifyWhatAKeySigned(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	require.NoError(t, err)
+
+	key := fromPrivateKey(privateKey)
+	sum := sha256.Sum256([]byte("ccanalytics"))
+
+	signature, err := key.Sign(sum[:])
+	require.NoError(t, err)
+	require.NoError(t, key.Verify(sum[:], signature))
+	require.NoError(t, Verify(key.PublicKey, sum[:], signature))
+}
+
+func TestShouldRejectTamperedSignatures(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	require.NoError(t, err)
+
+	key := fromPrivateKey(privateKey)
+	sum := sha256.Sum256([]byte("ccanalytics"))
+	other := sha256.Sum256([]byte("something else"))
+
+	// a valid signature, only over something else
+	signature, err := key.Sign(other[:])
+	require.NoError(t, err)
+	require.ErrorIs(t, key.Verify(sum[:], signature), ErrInvalidSignature)
+
+	require.ErrorIs(t, key.Verify(sum[:], "not base64"), ErrInvalidSignature)
+
+	// signed by someone else
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	forged := base64.StdEncoding.EncodeToString(ed25519.Sign(otherKey, sum[:]))
+	require.ErrorIs(t, key.Verify(sum[:], forged), ErrInvalidSignature)
+
+	_, err = ParsePublicKey("not base64")
+	require.ErrorIs(t, err, ErrInvalidPublicKey)
+}
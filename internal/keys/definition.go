@@ -0,0 +1,86 @@
+package keys
+
+import "time"
+
+type Status string
+
+const (
+	// StatusPending keys have been generated or imported but never used for signing
+	StatusPending Status = "pending"
+	// StatusActive is the single key currently used to sign new binaries
+	StatusActive Status = "active"
+	// StatusRotated keys were replaced by a newer key, their signatures are still trusted
+	StatusRotated Status = "rotated"
+	// StatusRetired keys were taken out of service, their signatures are still trusted
+	StatusRetired Status = "retired"
+	// StatusCompromised keys leaked, nothing they signed should be trusted
+	StatusCompromised Status = "compromised"
+)
+
+type Key struct {
+	ID          int    `sql:"id"`
+	Fingerprint string `sql:"fingerprint"`
+	Algorithm   string `sql:"algorithm"`
+	PublicKey   string `sql:"public_key"`
+	PrivateKey  string `sql:"private_key"`
+	Status      Status `sql:"status"`
+	ActivatedAt int64  `sql:"activated_at"`
+	RetiredAt   int64  `sql:"retired_at"`
+	CreatedAt   int64  `sql:"created_at"`
+	UpdatedAt   int64  `sql:"updated_at"`
+}
+
+func (k *Key) GetID() int {
+	return k.ID
+}
+
+func (k *Key) SetID(id int) {
+	k.ID = id
+}
+
+// PublicKeyInfo is the representation of a key that is safe to hand out to verifiers.
+type PublicKeyInfo struct {
+	KeyID       string `json:"key_id"`
+	Algorithm   string `json:"algorithm"`
+	PublicKey   string `json:"public_key"`
+	Status      Status `json:"status"`
+	ActivatedAt int64  `json:"activated_at,omitempty"`
+	RetiredAt   int64  `json:"retired_at,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+func (k *Key) Public() PublicKeyInfo {
+	return PublicKeyInfo{
+		KeyID:       k.Fingerprint,
+		Algorithm:   k.Algorithm,
+		PublicKey:   k.PublicKey,
+		Status:      k.Status,
+		ActivatedAt: k.ActivatedAt,
+		RetiredAt:   k.RetiredAt,
+		CreatedAt:   k.CreatedAt,
+	}
+}
+
+type KeyOptions func(*Key)
+
+func WithStatus(status Status) KeyOptions {
+	return func(k *Key) {
+		k.Status = status
+	}
+}
+
+func NewKey(opts ...KeyOptions) *Key {
+	k := &Key{
+		Algorithm: Algorithm,
+		Status:    StatusPending,
+	}
+
+	for _, opt := range opts {
+		opt(k)
+	}
+
+	k.CreatedAt = time.Now().UnixNano()
+	k.UpdatedAt = time.Now().UnixNano()
+
+	return k
+}
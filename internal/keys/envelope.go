@@ -0,0 +1,101 @@
+package keys
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrEnvelopeKeyMismatch = errors.New("envelope was signed by a different key")
+)
+
+// Envelope carries a JSON payload together with a detached signature over its compact encoding,
+// so documents handed to offline verifiers can be checked with nothing but a public key.
+// Whitespace is not significant, envelopes may be pretty printed without breaking the signature.
+type Envelope struct {
+	Payload   json.RawMessage `json:"payload"`
+	KeyID     string          `json:"key_id"`
+	Algorithm string          `json:"algorithm"`
+	Signature string          `json:"signature"`
+}
+
+// Seal marshals v and signs it with the active key.
+func Seal(ctx context.Context, v any) (*Envelope, error) {
+	k, err := GetActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if k == nil {
+		return nil, ErrNoActiveKey
+	}
+
+	return SealWith(k, v)
+}
+
+func SealWith(k *Key, v any) (*Envelope, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling envelope payload: %w", err)
+	}
+
+	signature, err := k.Sign(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		Payload:   payload,
+		KeyID:     k.Fingerprint,
+		Algorithm: k.Algorithm,
+		Signature: signature,
+	}, nil
+}
+
+// Open verifies the envelope against whichever of the given base64 public keys sealed it, and unmarshals its payload
+// into v. Verifiers pin the next key along with the current one before it is activated, so envelopes sealed on
+// either side of the rotation keep opening.
+func (e *Envelope) Open(publicKeys []string, v any) error {
+	publicKey, err := pinnedKey(publicKeys, e.KeyID)
+	if err != nil {
+		return err
+	}
+
+	var payload bytes.Buffer
+	if err := json.Compact(&payload, e.Payload); err != nil {
+		return fmt.Errorf("compacting envelope payload: %w", err)
+	}
+
+	if err := Verify(publicKey, payload.Bytes(), e.Signature); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(payload.Bytes(), v)
+}
+
+// pinnedKey finds the public key with the given fingerprint, every key given has to parse.
+func pinnedKey(publicKeys []string, fingerprint string) (string, error) {
+	var found string
+	pinned := make([]string, 0, len(publicKeys))
+
+	for _, publicKey := range publicKeys {
+		pk, err := ParsePublicKey(publicKey)
+		if err != nil {
+			return "", err
+		}
+
+		pinned = append(pinned, Fingerprint(pk))
+		if Fingerprint(pk) == fingerprint {
+			found = publicKey
+		}
+	}
+
+	if found == "" {
+		return "", fmt.Errorf("expected one of %v, got %s: %w", pinned, fingerprint, ErrEnvelopeKeyMismatch)
+	}
+
+	return found, nil
+}
@@ -0,0 +1,64 @@
+package keys
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
+
+const (
+	getKeyByIDQuery          = "select * from keys where id = ?;"
+	getKeyByFingerprintQuery = "select * from keys where fingerprint = ?;"
+	getActiveKeyQuery        = "select * from keys where status = 'active';"
+	listKeysQuery            = "select * from keys order by created_at;"
+)
+
+var (
+	ErrTooManyActiveKeys = fmt.Errorf("too many active keys")
+)
+
+func GetByID(ctx context.Context, id int) (*Key, error) {
+	return getOne(ctx, getKeyByIDQuery, id)
+}
+
+func GetByFingerprint(ctx context.Context, fingerprint string) (*Key, error) {
+	return getOne(ctx, getKeyByFingerprintQuery, fingerprint)
+}
+
+// GetActive returns the key new binaries should be signed with, or nil if none was activated yet.
+func GetActive(ctx context.Context) (*Key, error) {
+	keys, err := database.SelectContext[Key](ctx, getActiveKeyQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	if len(keys) > 1 {
+		slog.WarnContext(ctx, "Multiple active keys found, this should never happen", "count", len(keys))
+		return nil, ErrTooManyActiveKeys
+	}
+
+	return keys[0], nil
+}
+
+func List(ctx context.Context) ([]*Key, error) {
+	return database.SelectContext[Key](ctx, listKeysQuery)
+}
+
+func getOne(ctx context.Context, query string, args ...any) (*Key, error) {
+	keys, err := database.SelectContext[Key](ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	return keys[0], nil
+}
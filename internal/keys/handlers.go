@@ -0,0 +1,10 @@
+package keys
+
+import (
+	"github.com/labstack/echo/v5"
+)
+
+func Urls(e *echo.Echo) {
+	e.GET("/keys", ListHandler)
+	e.GET("/keys/active", ActiveHandler)
+}
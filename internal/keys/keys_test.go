@@ -0,0 +1,145 @@
+package keys
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database/migrator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	ctx := context.Background()
+
+	dir, err := os.MkdirTemp("", "ccanalytics-keys-test")
+	if err != nil {
+		slog.Error("Failed to create temp dir", "error", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	cleanup, err := database.Connect(ctx, config.DatabaseConfig{
+		Path:        filepath.Join(dir, "app.db"),
+		JournalMode: "wal",
+		ForeignKeys: true,
+	})
+	if err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		return 1
+	}
+	defer cleanup()
+
+	if err := migrator.MigrateUp(ctx); err != nil {
+		slog.Error("Failed to migrate database", "error", err)
+		return 1
+	}
+
+	return m.Run()
+}
+
+func TestShouldRotateTheActiveKey(t *testing.T) {
+	ctx := context.Background()
+
+	pending, err := Generate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, StatusPending, pending.Status)
+
+	first, err := Activate(ctx, pending.Fingerprint)
+	require.NoError(t, err)
+	require.Equal(t, StatusActive, first.Status)
+	require.NotZero(t, first.ActivatedAt)
+
+	second, err := Rotate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, StatusActive, second.Status)
+	require.NotEqual(t, first.Fingerprint, second.Fingerprint)
+
+	active, err := GetActive(ctx)
+	require.NoError(t, err)
+	require.Equal(t, second.Fingerprint, active.Fingerprint)
+
+	first, err = GetByFingerprint(ctx, first.Fingerprint)
+	require.NoError(t, err)
+	require.Equal(t, StatusRotated, first.Status)
+
+	// rotated keys can be brought back
+	first, err = Activate(ctx, first.Fingerprint)
+	require.NoError(t, err)
+	require.Equal(t, StatusActive, first.Status)
+
+	second, err = GetByFingerprint(ctx, second.Fingerprint)
+	require.NoError(t, err)
+	require.Equal(t, StatusRotated, second.Status)
+}
+
+func TestShouldRetireKeys(t *testing.T) {
+	ctx := context.Background()
+
+	k, err := Generate(ctx)
+	require.NoError(t, err)
+
+	retired, err := Retire(ctx, k.Fingerprint, false)
+	require.NoError(t, err)
+	require.Equal(t, StatusRetired, retired.Status)
+	require.NotZero(t, retired.RetiredAt)
+
+	_, err = Retire(ctx, k.Fingerprint, false)
+	require.ErrorIs(t, err, ErrKeyAlreadyRetired)
+
+	_, err = Activate(ctx, k.Fingerprint)
+	require.ErrorIs(t, err, ErrKeyNotActivatable)
+
+	// a retired key found to have leaked later on
+	compromised, err := Retire(ctx, k.Fingerprint, true)
+	require.NoError(t, err)
+	require.Equal(t, StatusCompromised, compromised.Status)
+
+	_, err = Retire(ctx, k.Fingerprint, false)
+	require.ErrorIs(t, err, ErrKeyAlreadyRetired)
+
+	_, err = Retire(ctx, k.Fingerprint, true)
+	require.ErrorIs(t, err, ErrKeyAlreadyRetired)
+
+	_, err = Activate(ctx, k.Fingerprint)
+	require.ErrorIs(t, err, ErrKeyNotActivatable)
+
+	_, err = Retire(ctx, "missing", true)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestShouldOpenOnlyWhatTheKeySealed(t *testing.T) {
+	ctx := context.Background()
+
+	k, err := Generate(ctx)
+	require.NoError(t, err)
+
+	other, err := Generate(ctx)
+	require.NoError(t, err)
+
+	envelope, err := SealWith(k, map[string]string{"hello": "world"})
+	require.NoError(t, err)
+
+	var payload map[string]string
+	require.NoError(t, envelope.Open([]string{k.PublicKey}, &payload))
+	require.Equal(t, "world", payload["hello"])
+
+	// any of the pinned keys will do
+	payload = nil
+	require.NoError(t, envelope.Open([]string{other.PublicKey, k.PublicKey}, &payload))
+	require.Equal(t, "world", payload["hello"])
+
+	require.ErrorIs(t, envelope.Open([]string{other.PublicKey}, &payload), ErrEnvelopeKeyMismatch)
+	require.ErrorIs(t, envelope.Open(nil, &payload), ErrEnvelopeKeyMismatch)
+
+	envelope.Payload = []byte(`{"hello":"mallory"}`)
+	require.ErrorIs(t, envelope.Open([]string{k.PublicKey}, &payload), ErrInvalidSignature)
+}
@@ -0,0 +1,121 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
+
+const (
+	rotateActiveKeyQuery = "update keys set status = 'rotated', updated_at = ? where status = 'active';"
+	activateKeyQuery     = "update keys set status = 'active', activated_at = ?, updated_at = ? where id = ?;"
+	retireKeyQuery       = "update keys set status = ?, retired_at = ?, updated_at = ? where id = ?;"
+)
+
+var (
+	ErrKeyNotFound       = errors.New("key not found")
+	ErrNoActiveKey       = errors.New("no active signing key, generate or import one with the keys command")
+	ErrKeyNotActivatable = errors.New("only pending or rotated keys can be activated")
+	ErrKeyAlreadyRetired = errors.New("key has already been retired")
+)
+
+// Generate creates a brand new key, it is only used for signing once activated.
+func Generate(ctx context.Context) (*Key, error) {
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+
+	return Create(ctx, fromPrivateKey(privateKey))
+}
+
+// Import stores an existing base64 encoded Ed25519 key, as accepted by ParsePrivateKey.
+func Import(ctx context.Context, encoded string) (*Key, error) {
+	privateKey, err := ParsePrivateKey(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return Create(ctx, fromPrivateKey(privateKey))
+}
+
+// Activate makes the given key the signing key, the previously active key (if any) is marked as rotated.
+func Activate(ctx context.Context, fingerprint string) (*Key, error) {
+	k, err := GetByFingerprint(ctx, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	if k == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	if k.Status == StatusActive {
+		return k, nil
+	}
+
+	if k.Status != StatusPending && k.Status != StatusRotated {
+		return nil, fmt.Errorf("key %s is %s: %w", k.Fingerprint, k.Status, ErrKeyNotActivatable)
+	}
+
+	err = database.WithinTransaction(ctx, func(ctx context.Context) error {
+		now := time.Now().UnixNano()
+
+		if _, err := database.ExecContext(ctx, rotateActiveKeyQuery, now); err != nil {
+			return fmt.Errorf("rotating previous active key: %w", err)
+		}
+
+		if _, err := database.ExecContext(ctx, activateKeyQuery, now, now, k.ID); err != nil {
+			return fmt.Errorf("activating key %s: %w", k.Fingerprint, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return GetByID(ctx, k.ID)
+}
+
+// Rotate generates a new key and activates it in place of the current one.
+func Rotate(ctx context.Context) (*Key, error) {
+	k, err := Generate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return Activate(ctx, k.Fingerprint)
+}
+
+// Retire takes a key out of service. Compromised keys are flagged so verifiers stop trusting their signatures.
+func Retire(ctx context.Context, fingerprint string, compromised bool) (*Key, error) {
+	k, err := GetByFingerprint(ctx, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	if k == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	status := StatusRetired
+	if compromised {
+		status = StatusCompromised
+	}
+
+	// A retired key may still be flagged as compromised later on, but never the other way around
+	if k.Status == StatusCompromised || (k.Status == StatusRetired && !compromised) {
+		return nil, fmt.Errorf("key %s is %s: %w", k.Fingerprint, k.Status, ErrKeyAlreadyRetired)
+	}
+
+	now := time.Now().UnixNano()
+	if _, err := database.ExecContext(ctx, retireKeyQuery, status, now, now, k.ID); err != nil {
+		return nil, fmt.Errorf("retiring key %s: %w", k.Fingerprint, err)
+	}
+
+	return GetByID(ctx, k.ID)
+}
@@ -0,0 +1,43 @@
+package keys
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+)
+
+func ListHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	keys, err := List(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list keys", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list keys")
+	}
+
+	publicKeys := make([]PublicKeyInfo, 0, len(keys))
+	for _, k := range keys {
+		publicKeys = append(publicKeys, k.Public())
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"keys": publicKeys,
+	})
+}
+
+func ActiveHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	k, err := GetActive(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get active key", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get active key")
+	}
+
+	if k == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "no active key")
+	}
+
+	return c.JSON(http.StatusOK, k.Public())
+}
@@ -1,15 +1,23 @@
 package migrations
 
-import "time"
+import (
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
 
 type Migration struct {
 	ID        int    `sql:"id"`
 	Filename  string `sql:"filename"`
 	Timestamp int64  `sql:"timestamp"`
+	// Hash is the checksum of the up section that was applied, empty for migrations applied before it was recorded
+	Hash      string `sql:"hash"`
 	CreatedAt int64  `sql:"created_at"`
 	UpdatedAt int64  `sql:"updated_at"`
 }
 
+var migrationRepository = database.NewRepository[Migration]("migrations")
+
 func (m *Migration) GetID() int {
 	return m.ID
 }
@@ -32,6 +40,12 @@ func WithTimestamp(timestamp int64) MigrationOptions {
 	}
 }
 
+func WithHash(hash string) MigrationOptions {
+	return func(m *Migration) {
+		m.Hash = hash
+	}
+}
+
 func NewMigration(opts ...MigrationOptions) *Migration {
 	m := &Migration{}
 
@@ -0,0 +1,8 @@
+package migrations
+
+import "context"
+
+// Delete removes the record of an applied migration, once its down section has been run.
+func Delete(ctx context.Context, migration *Migration) error {
+	return migrationRepository.Delete(ctx, migration.ID)
+}
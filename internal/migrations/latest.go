@@ -2,6 +2,7 @@ package migrations
 
 import (
 	"context"
+	"errors"
 
 	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
 )
@@ -15,7 +16,7 @@ func GetLatestAppliedMigrations(ctx context.Context) ([]*Migration, error) {
 	migrations, err := database.SelectContext[Migration](ctx, getLatestAppliedMigrationsQuery)
 
 	// If the err is no table found, we can ignore it and return an empty slice of migrations
-	if database.IsNoTableFoundError(err) {
+	if errors.Is(err, database.ErrNoSuchTable) {
 		return []*Migration{}, nil
 	}
 	if err != nil {
@@ -29,7 +30,7 @@ func GetLastMigration(ctx context.Context) (*Migration, error) {
 	migrations, err := database.SelectContext[Migration](ctx, getLastAppliedMigrationQuery)
 
 	// If the err is no table found, we can ignore it and return an empty slice of migrations
-	if database.IsNoTableFoundError(err) {
+	if errors.Is(err, database.ErrNoSuchTable) {
 		return nil, nil
 	}
 	if err != nil {
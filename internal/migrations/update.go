@@ -0,0 +1,7 @@
+package migrations
+
+import "context"
+
+func Update(ctx context.Context, migration *Migration) error {
+	return migrationRepository.Update(ctx, migration)
+}
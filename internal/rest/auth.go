@@ -0,0 +1,26 @@
+package rest
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/labstack/echo/v5"
+)
+
+// RequireToken rejects requests without the configured bearer token, it is a no-op when no token is configured.
+func RequireToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		if config.Rest.AuthToken == "" {
+			return next(c)
+		}
+
+		token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(config.Rest.AuthToken)) != 1 {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid or missing token")
+		}
+
+		return next(c)
+	}
+}
@@ -3,4 +3,5 @@ package contextkey
 var (
 	RequestIDKey = "request_id"
 	LocationKey  = "location"
+	ClientKey    = "client"
 )
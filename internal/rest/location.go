@@ -2,43 +2,24 @@ package rest
 
 import (
 	"context"
-	"strconv"
-	"strings"
 
 	"github.com/Gustrb/ccanalytics/internal/rest/contextkey"
 	"github.com/Gustrb/ccanalytics/internal/rest/location"
 	"github.com/labstack/echo/v5"
 )
 
-func WithLocation(next echo.HandlerFunc) echo.HandlerFunc {
-	parseBool := func(value string) *bool {
-		value = strings.TrimSpace(value)
+// WithLocation puts where the request came from, as far as chain can tell, in the context. The location is never
+// nil, its fields are empty when no resolver knew anything.
+func WithLocation(chain *location.Chain) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			loc := chain.Resolve(c.Request())
 
-		boolVal, err := strconv.ParseBool(value)
-		if err != nil {
-			return nil
-		}
-
-		return &boolVal
-	}
+			setContext(c, func(ctx context.Context) context.Context {
+				return context.WithValue(ctx, contextkey.LocationKey, loc)
+			})
 
-	return func(c *echo.Context) error {
-		header := c.Request().Header
-
-		loc := location.Location{
-			City:        strings.TrimSpace(header.Get("Cloudfront-Viewer-City")),
-			CountryCode: strings.TrimSpace(header.Get("Cloudfront-Viewer-Country")),
-			CountryName: strings.TrimSpace(header.Get("Cloudfront-Viewer-Country-Name")),
-			TimeZone:    strings.TrimSpace(header.Get("Cloudfront-Viewer-Time-Zone")),
-			IsDesktop:   parseBool(header.Get("Cloudfront-Is-Desktop-Viewer")),
-			IsMobile:    parseBool(header.Get("Cloudfront-Is-Mobile-Viewer")),
-			IsTablet:    parseBool(header.Get("Cloudfront-Is-Tablet-Viewer")),
+			return next(c)
 		}
-
-		setContext(c, func(ctx context.Context) context.Context {
-			return context.WithValue(ctx, contextkey.LocationKey, &loc)
-		})
-
-		return next(c)
 	}
 }
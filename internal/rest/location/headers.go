@@ -0,0 +1,126 @@
+package location
+
+import (
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// trustedHeaders hides the headers of requests that didn't come straight from a trusted proxy from a CDN resolver,
+// anyone reaching the api some other way could have set them.
+type trustedHeaders struct {
+	Resolver
+	trusted []netip.Prefix
+}
+
+func (t trustedHeaders) Resolve(r *http.Request) *Location {
+	if !isTrusted(remoteIP(r), t.trusted) {
+		return nil
+	}
+
+	return t.Resolver.Resolve(r)
+}
+
+// CloudFront reads the Cloudfront-Viewer-* headers, and the device ones, the distribution is set up to forward.
+type CloudFront struct{}
+
+func (CloudFront) Name() string {
+	return ResolverCloudFront
+}
+
+func (CloudFront) Resolve(r *http.Request) *Location {
+	header := r.Header
+
+	loc := &Location{
+		City:        strings.TrimSpace(header.Get("Cloudfront-Viewer-City")),
+		CountryCode: countryCode(header.Get("Cloudfront-Viewer-Country")),
+		CountryName: strings.TrimSpace(header.Get("Cloudfront-Viewer-Country-Name")),
+		TimeZone:    strings.TrimSpace(header.Get("Cloudfront-Viewer-Time-Zone")),
+		IsDesktop:   parseBool(header.Get("Cloudfront-Is-Desktop-Viewer")),
+		IsMobile:    parseBool(header.Get("Cloudfront-Is-Mobile-Viewer")),
+		IsTablet:    parseBool(header.Get("Cloudfront-Is-Tablet-Viewer")),
+	}
+
+	// the device headers are worth keeping on their own, they are forwarded separately from the geo ones
+	if loc.CountryCode == "" && loc.City == "" && loc.IsDesktop == nil && loc.IsMobile == nil && loc.IsTablet == nil {
+		return nil
+	}
+
+	return loc
+}
+
+// Cloudflare reads CF-IPCountry, and the city and time zone the "visitor location headers" transform adds.
+type Cloudflare struct{}
+
+func (Cloudflare) Name() string {
+	return ResolverCloudflare
+}
+
+func (Cloudflare) Resolve(r *http.Request) *Location {
+	header := r.Header
+
+	loc := &Location{
+		City:        strings.TrimSpace(header.Get("Cf-Ipcity")),
+		CountryCode: countryCode(header.Get("Cf-Ipcountry")),
+		TimeZone:    strings.TrimSpace(header.Get("Cf-Timezone")),
+	}
+
+	if loc.CountryCode == "" && loc.City == "" {
+		return nil
+	}
+
+	return loc
+}
+
+// Fastly has no geo headers of its own, it reads the ones a VCL snippet sets out of client.geo:
+//
+//	set req.http.Fastly-Geo-Country-Code = client.geo.country_code;
+//	set req.http.Fastly-Geo-Country-Name = client.geo.country_name.utf8;
+//	set req.http.Fastly-Geo-City = client.geo.city.utf8;
+type Fastly struct{}
+
+func (Fastly) Name() string {
+	return ResolverFastly
+}
+
+func (Fastly) Resolve(r *http.Request) *Location {
+	header := r.Header
+
+	loc := &Location{
+		City:        strings.TrimSpace(header.Get("Fastly-Geo-City")),
+		CountryCode: countryCode(header.Get("Fastly-Geo-Country-Code")),
+		CountryName: strings.TrimSpace(header.Get("Fastly-Geo-Country-Name")),
+	}
+
+	if loc.CountryCode == "" && loc.City == "" {
+		return nil
+	}
+
+	return loc
+}
+
+// countryCode keeps ISO 3166 alpha-2 codes only, the CDNs use XX, ** or T1 (Tor) when they don't know.
+func countryCode(value string) string {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) != 2 || value == "XX" {
+		return ""
+	}
+
+	for _, r := range value {
+		if r < 'A' || r > 'Z' {
+			return ""
+		}
+	}
+
+	return value
+}
+
+func parseBool(value string) *bool {
+	boolVal, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return nil
+	}
+
+	return &boolVal
+}
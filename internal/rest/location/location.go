@@ -10,6 +10,8 @@ type Location struct {
 	IsDesktop   *bool
 	IsMobile    *bool
 	IsTablet    *bool
+	// Resolver is the name of the resolver that found the location, empty when none did
+	Resolver string
 }
 
 func (l *Location) LogValue() slog.Value {
@@ -42,6 +44,10 @@ func (l *Location) LogValue() slog.Value {
 		attrs = append(attrs, slog.Bool("is_tablet", *l.IsTablet))
 	}
 
+	if l.Resolver != "" {
+		attrs = append(attrs, slog.String("resolver", l.Resolver))
+	}
+
 	return slog.GroupValue(attrs...)
 }
 
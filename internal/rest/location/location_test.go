@@ -0,0 +1,206 @@
+package location
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldUseTheFirstResolverThatKnowsTheLocation(t *testing.T) {
+	chain, err := NewChain(config.LocationConfig{
+		Resolvers: []string{ResolverCloudFront, ResolverCloudflare, ResolverFastly, ResolverMMDB},
+		// where httptest requests come from
+		TrustedProxies: []string{"192.0.2.0/24"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, chain.Close()) })
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected Location
+	}{
+		{
+			name:     "nothing known",
+			expected: Location{},
+		},
+		{
+			name: "cloudfront first",
+			headers: map[string]string{
+				"Cloudfront-Viewer-Country": "br",
+				"Cloudfront-Viewer-City":    "Porto Alegre",
+				"CF-IPCountry":              "US",
+			},
+			expected: Location{CountryCode: "BR", City: "Porto Alegre", Resolver: ResolverCloudFront},
+		},
+		{
+			name: "cloudflare without cloudfront",
+			headers: map[string]string{
+				"CF-IPCountry":            "DE",
+				"CF-Timezone":             "Europe/Berlin",
+				"Fastly-Geo-Country-Code": "FR",
+			},
+			expected: Location{CountryCode: "DE", TimeZone: "Europe/Berlin", Resolver: ResolverCloudflare},
+		},
+		{
+			name: "unknown countries are skipped",
+			headers: map[string]string{
+				"CF-IPCountry":            "XX",
+				"Fastly-Geo-Country-Code": "FR",
+				"Fastly-Geo-Country-Name": "France",
+			},
+			expected: Location{CountryCode: "FR", CountryName: "France", Resolver: ResolverFastly},
+		},
+		{
+			name: "tor exits are not a country",
+			headers: map[string]string{
+				"CF-IPCountry": "T1",
+			},
+			expected: Location{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			require.Equal(t, &tt.expected, chain.Resolve(req))
+		})
+	}
+}
+
+func TestShouldIgnoreCDNHeadersOutsideOfTrustedProxies(t *testing.T) {
+	chain, err := NewChain(config.LocationConfig{
+		Resolvers:      []string{ResolverCloudFront, ResolverCloudflare, ResolverFastly},
+		TrustedProxies: []string{"10.0.0.0/8"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, chain.Close()) })
+
+	forge := func(remoteAddr string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Cloudfront-Viewer-Country", "BR")
+		req.Header.Set("CF-IPCountry", "DE")
+		req.Header.Set("Fastly-Geo-Country-Code", "FR")
+
+		return req
+	}
+
+	require.Equal(t, &Location{}, chain.Resolve(forge("203.0.113.7:4711")))
+	require.Equal(t, &Location{CountryCode: "BR", Resolver: ResolverCloudFront}, chain.Resolve(forge("10.1.2.3:4711")))
+
+	require.False(t, chain.TrustsAnyHeaders())
+
+	// without trusted proxies everyone is believed, as before there was a way to tell them apart
+	chain, err = NewChain(config.LocationConfig{Resolvers: []string{ResolverCloudFront}})
+	require.NoError(t, err)
+	require.True(t, chain.TrustsAnyHeaders())
+	require.Equal(t, &Location{CountryCode: "BR", Resolver: ResolverCloudFront}, chain.Resolve(forge("203.0.113.7:4711")))
+
+	// only the CDN resolvers read headers
+	chain, err = NewChain(config.LocationConfig{Resolvers: []string{ResolverMMDB}})
+	require.NoError(t, err)
+	require.False(t, chain.TrustsAnyHeaders())
+}
+
+func TestShouldKeepCloudFrontDeviceHeadersWithoutACountry(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Cloudfront-Is-Mobile-Viewer", "true")
+
+	loc := CloudFront{}.Resolve(req)
+	require.NotNil(t, loc)
+	require.NotNil(t, loc.IsMobile)
+	require.True(t, *loc.IsMobile)
+}
+
+func TestShouldRefuseBadChains(t *testing.T) {
+	_, err := NewChain(config.LocationConfig{Resolvers: []string{ResolverCloudFront, "akamai"}})
+	require.ErrorIs(t, err, ErrUnknownResolver)
+
+	_, err = NewChain(config.LocationConfig{
+		Resolvers: []string{ResolverMMDB},
+		MMDBPath:  filepath.Join(t.TempDir(), "missing.mmdb"),
+	})
+	require.Error(t, err)
+
+	_, err = NewChain(config.LocationConfig{
+		Resolvers:      []string{ResolverMMDB},
+		MMDBPath:       filepath.Join(t.TempDir(), "missing.mmdb"),
+		TrustedProxies: []string{"10.0.0.0/33"},
+	})
+	require.ErrorContains(t, err, "10.0.0.0/33")
+}
+
+func TestShouldOnlyFollowForwardedForThroughTrustedProxies(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 192.168.1.1 ", "::ffff:172.16.0.1"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		expected   string
+	}{
+		{
+			name:       "direct",
+			remoteAddr: "203.0.113.7:4711",
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "untrusted remote can't forward",
+			remoteAddr: "203.0.113.7:4711",
+			forwarded:  []string{"198.51.100.1"},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "through one proxy",
+			remoteAddr: "10.1.2.3:4711",
+			forwarded:  []string{"198.51.100.1"},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "spoofed entries left of the client are ignored",
+			remoteAddr: "10.1.2.3:4711",
+			forwarded:  []string{"1.1.1.1, 198.51.100.1", "192.168.1.1"},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "mapped addresses",
+			remoteAddr: "[::ffff:172.16.0.1]:4711",
+			forwarded:  []string{"::ffff:198.51.100.1"},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "malformed entry stops the walk",
+			remoteAddr: "10.1.2.3:4711",
+			forwarded:  []string{"198.51.100.1, unknown"},
+			expected:   "10.1.2.3",
+		},
+		{
+			name:       "only proxies",
+			remoteAddr: "10.1.2.3:4711",
+			forwarded:  []string{"10.9.9.9"},
+			expected:   "10.9.9.9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			require.Equal(t, tt.expected, ClientIP(req, trusted).String())
+		})
+	}
+}
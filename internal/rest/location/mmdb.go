@@ -0,0 +1,172 @@
+package location
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// MMDB looks the client address up in a local MaxMind format database, for requests that didn't come through a
+// CDN that tells us where they are from.
+type MMDB struct {
+	reader  *maxminddb.Reader
+	trusted []netip.Prefix
+}
+
+// mmdbRecord is the part of a GeoIP2 or GeoLite2 City record we use, Country databases only fill in the country.
+type mmdbRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Location struct {
+		TimeZone string `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+}
+
+// OpenMMDB opens the database at path, X-Forwarded-For is only followed through the trusted proxies, given as
+// addresses or CIDR ranges.
+func OpenMMDB(path string, trustedProxies []string) (*MMDB, error) {
+	trusted, err := ParseTrustedProxies(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening location database %s: %w", path, err)
+	}
+
+	return &MMDB{reader: reader, trusted: trusted}, nil
+}
+
+func (m *MMDB) Name() string {
+	return ResolverMMDB
+}
+
+func (m *MMDB) Resolve(r *http.Request) *Location {
+	ip := ClientIP(r, m.trusted)
+	if !ip.IsValid() {
+		return nil
+	}
+
+	result := m.reader.Lookup(ip)
+	if !result.Found() {
+		return nil
+	}
+
+	var record mmdbRecord
+	if err := result.Decode(&record); err != nil {
+		slog.WarnContext(r.Context(), "Failed to decode location record", "ip", ip, "error", err)
+		return nil
+	}
+
+	loc := &Location{
+		City:        record.City.Names["en"],
+		CountryCode: record.Country.ISOCode,
+		CountryName: record.Country.Names["en"],
+		TimeZone:    record.Location.TimeZone,
+	}
+
+	if loc.CountryCode == "" && loc.City == "" {
+		return nil
+	}
+
+	return loc
+}
+
+func (m *MMDB) Close() error {
+	return m.reader.Close()
+}
+
+// ParseTrustedProxies accepts both plain addresses and CIDR ranges.
+func ParseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("parsing trusted proxy %q: %w", proxy, err)
+			}
+
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parsing trusted proxy %q: %w", proxy, err)
+		}
+
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// ClientIP is the address the request came from. X-Forwarded-For is walked from the right for as long as the hop
+// that added the entry is a trusted proxy, so a client can't pick its own address by sending the header. The
+// result is invalid when the remote address can't be parsed.
+func ClientIP(r *http.Request, trusted []netip.Prefix) netip.Addr {
+	ip := remoteIP(r)
+	if !ip.IsValid() {
+		return ip
+	}
+
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0 && isTrusted(ip, trusted); i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// whatever is left of a malformed entry can't be told apart from what the client made up
+			break
+		}
+
+		ip = hop.Unmap()
+	}
+
+	return ip
+}
+
+// remoteIP is the address of the other end of the connection, invalid when it can't be parsed.
+func remoteIP(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	return ip.Unmap()
+}
+
+func isTrusted(ip netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,110 @@
+package location
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Gustrb/ccanalytics/internal/config"
+)
+
+const (
+	ResolverCloudFront = "cloudfront"
+	ResolverCloudflare = "cloudflare"
+	ResolverFastly     = "fastly"
+	ResolverMMDB       = "mmdb"
+)
+
+var ErrUnknownResolver = errors.New("resolver must be one of cloudfront, cloudflare, fastly or mmdb")
+
+// Resolver finds out where a request came from by one means.
+type Resolver interface {
+	Name() string
+	// Resolve returns nil when the resolver knows nothing about the request
+	Resolve(r *http.Request) *Location
+}
+
+// Chain asks its resolvers in order, the first one that knows where the request came from wins.
+type Chain struct {
+	resolvers []Resolver
+	closers   []io.Closer
+
+	trustsAnyHeaders bool
+}
+
+// NewChain builds the chain cfg asks for, it must be closed once no request uses it anymore. Once trusted proxies
+// are set, the CDN resolvers only read the headers of requests coming straight from one of them. Without any they
+// read the headers of every request, as they always did, see TrustsAnyHeaders.
+func NewChain(cfg config.LocationConfig) (*Chain, error) {
+	trusted, err := ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := &Chain{}
+
+	// an api only reachable through its CDN has nothing to gate
+	gate := func(resolver Resolver) Resolver {
+		if len(trusted) == 0 {
+			chain.trustsAnyHeaders = true
+			return resolver
+		}
+
+		return trustedHeaders{resolver, trusted}
+	}
+
+	for _, name := range cfg.Resolvers {
+		switch name {
+		case ResolverCloudFront:
+			chain.resolvers = append(chain.resolvers, gate(CloudFront{}))
+		case ResolverCloudflare:
+			chain.resolvers = append(chain.resolvers, gate(Cloudflare{}))
+		case ResolverFastly:
+			chain.resolvers = append(chain.resolvers, gate(Fastly{}))
+		case ResolverMMDB:
+			if cfg.MMDBPath == "" {
+				continue
+			}
+
+			mmdb, err := OpenMMDB(cfg.MMDBPath, cfg.TrustedProxies)
+			if err != nil {
+				return nil, errors.Join(err, chain.Close())
+			}
+
+			chain.resolvers = append(chain.resolvers, mmdb)
+			chain.closers = append(chain.closers, mmdb)
+		default:
+			return nil, errors.Join(fmt.Errorf("%q: %w", name, ErrUnknownResolver), chain.Close())
+		}
+	}
+
+	return chain, nil
+}
+
+// TrustsAnyHeaders is true when a CDN resolver reads the headers of every request, because no trusted proxies were
+// set. Anyone reaching the api without going through the CDN can then pick their own location.
+func (c *Chain) TrustsAnyHeaders() bool {
+	return c.trustsAnyHeaders
+}
+
+// Resolve never returns nil, the location is empty when no resolver knew anything.
+func (c *Chain) Resolve(r *http.Request) *Location {
+	for _, resolver := range c.resolvers {
+		if loc := resolver.Resolve(r); loc != nil {
+			loc.Resolver = resolver.Name()
+			return loc
+		}
+	}
+
+	return &Location{}
+}
+
+func (c *Chain) Close() error {
+	var errs []error
+	for _, closer := range c.closers {
+		errs = append(errs, closer.Close())
+	}
+
+	return errors.Join(errs...)
+}
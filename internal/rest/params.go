@@ -0,0 +1,25 @@
+package rest
+
+import (
+	"strconv"
+	"time"
+)
+
+// ParseTimeParam accepts either an RFC 3339 date or unix nanoseconds, matching the timestamps the API returns.
+// An empty value is zero.
+func ParseTimeParam(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	if nanos, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return nanos, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.UnixNano(), nil
+}
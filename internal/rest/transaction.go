@@ -1,32 +1,77 @@
 package rest
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"slices"
+	"strings"
 
 	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
 	"github.com/labstack/echo/v5"
 )
 
+var (
+	errRollback = errors.New("request failed, rolling back its transaction")
+	errAnswered = errors.New("request was already answered")
+)
+
 var whitelistedRoutes = []string{
 	"/",
 	"/health",
 }
 
+// WithTransaction runs the rest of the chain in a transaction, committed unless the request fails. A transaction
+// that gets busy is run again from the start, as long as nothing was written back to the caller yet.
 func WithTransaction(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		if slices.Contains(whitelistedRoutes, c.Path()) {
 			return next(c)
 		}
 
-		err := database.WithinTransaction(c.Request().Context(), func(ctx context.Context) error {
-			// exec the next handler with the transaction context
-			if err := next(c); err != nil {
-				return err
+		body := replayable(c.Request())
+		attempts := 0
+
+		err := database.RetryOnBusy(c.Request().Context(), func(ctx context.Context) error {
+			if attempts++; attempts > 1 {
+				body.rewind()
 			}
 
-			return nil
+			err := database.WithinTransaction(ctx, func(ctx context.Context) error {
+				// exec the next handler with the transaction context
+				setContext(c, func(context.Context) context.Context {
+					return ctx
+				})
+
+				if err := next(c); err != nil {
+					return err
+				}
+
+				// failed requests are answered without an error once logged, look at the status to undo their writes
+				if res, err := echo.UnwrapResponse(c.Response()); err == nil && res.Status >= http.StatusBadRequest {
+					return errRollback
+				}
+
+				return nil
+			})
+
+			if errors.Is(err, errRollback) {
+				return errRollback
+			}
+
+			if res, rerr := echo.UnwrapResponse(c.Response()); errors.Is(err, database.ErrBusy) && rerr == nil && res.Committed {
+				// running it again would answer twice
+				return fmt.Errorf("%w: %v", errAnswered, err)
+			}
+
+			return err
 		})
+		if errors.Is(err, errRollback) {
+			return nil
+		}
 		if err != nil {
 			return echo.NewHTTPError(500, "An error occurred while processing the request").Wrap(err)
 		}
@@ -34,3 +79,45 @@ func WithTransaction(next echo.HandlerFunc) echo.HandlerFunc {
 		return nil
 	}
 }
+
+// replayableBody keeps what the handler read from the request body, so a busy request can read it again.
+type replayableBody struct {
+	body   io.ReadCloser
+	reader io.Reader
+	read   bytes.Buffer
+}
+
+// replayable makes the body of r readable again on retries. Multipart forms are left alone, the request keeps
+// them once parsed and they can be as large as the files uploaded in them.
+func replayable(r *http.Request) *replayableBody {
+	if r.Body == nil || r.Body == http.NoBody || strings.HasPrefix(r.Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		return nil
+	}
+
+	b := &replayableBody{body: r.Body, reader: r.Body}
+	r.Body = b
+
+	return b
+}
+
+func (b *replayableBody) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	b.read.Write(p[:n])
+
+	return n, err
+}
+
+func (b *replayableBody) Close() error {
+	return b.body.Close()
+}
+
+// rewind starts the body over, what was read already is read from memory before the rest of the body.
+func (b *replayableBody) rewind() {
+	if b == nil {
+		return
+	}
+
+	read := bytes.Clone(b.read.Bytes())
+	b.read.Reset()
+	b.reader = io.MultiReader(bytes.NewReader(read), b.body)
+}
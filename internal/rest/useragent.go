@@ -0,0 +1,22 @@
+package rest
+
+import (
+	"context"
+
+	"github.com/Gustrb/ccanalytics/internal/rest/contextkey"
+	"github.com/Gustrb/ccanalytics/internal/rest/useragent"
+	"github.com/labstack/echo/v5"
+)
+
+// WithUserAgent puts what the User-Agent header tells about the caller in the context, next to the location.
+func WithUserAgent(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		client := useragent.Parse(c.Request().UserAgent())
+
+		setContext(c, func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, contextkey.ClientKey, client)
+		})
+
+		return next(c)
+	}
+}
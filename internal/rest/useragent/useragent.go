@@ -0,0 +1,324 @@
+package useragent
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+type Kind string
+
+const (
+	KindBrowser Kind = "browser"
+	// KindSDK is our own Go SDK, used directly
+	KindSDK Kind = "sdk"
+	// KindCLI is one of our own commands talking to a remote API
+	KindCLI Kind = "cli"
+	// KindHTTPClient is a generic client such as curl or a language's standard library
+	KindHTTPClient Kind = "http_client"
+	KindBot        Kind = "bot"
+	KindUnknown    Kind = "unknown"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+)
+
+// Client is what the User-Agent header tells about the caller, fields it doesn't tell are empty.
+type Client struct {
+	Kind    Kind
+	Name    string
+	Version string
+	OS      string
+	// Device is only known for browsers
+	Device string
+	// Command is the ccanalytics-cli command that made the request
+	Command string
+	// CI is the CI service our own clients say they run on
+	CI string
+}
+
+func (c *Client) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("kind", string(c.Kind))}
+
+	if c.Name != "" {
+		attrs = append(attrs, slog.String("name", c.Name))
+	}
+
+	if c.Version != "" {
+		attrs = append(attrs, slog.String("version", c.Version))
+	}
+
+	if c.OS != "" {
+		attrs = append(attrs, slog.String("os", c.OS))
+	}
+
+	if c.Device != "" {
+		attrs = append(attrs, slog.String("device", c.Device))
+	}
+
+	if c.Command != "" {
+		attrs = append(attrs, slog.String("command", c.Command))
+	}
+
+	if c.CI != "" {
+		attrs = append(attrs, slog.String("ci", c.CI))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+func (c *Client) String() string {
+	return c.LogValue().String()
+}
+
+// httpClients are matched on the lowercased name of the first product.
+var httpClients = map[string]bool{
+	"curl":              true,
+	"wget":              true,
+	"httpie":            true,
+	"python-requests":   true,
+	"python-urllib":     true,
+	"python-httpx":      true,
+	"aiohttp":           true,
+	"go-http-client":    true,
+	"okhttp":            true,
+	"axios":             true,
+	"node-fetch":        true,
+	"node":              true,
+	"undici":            true,
+	"java":              true,
+	"apache-httpclient": true,
+	"libwww-perl":       true,
+	"ruby":              true,
+	"faraday":           true,
+}
+
+// goosNames are how our own clients report runtime.GOOS.
+var goosNames = map[string]string{
+	"linux":   "Linux",
+	"darwin":  "macOS",
+	"windows": "Windows",
+	"freebsd": "FreeBSD",
+	"openbsd": "OpenBSD",
+	"netbsd":  "NetBSD",
+	"android": "Android",
+	"ios":     "iOS",
+}
+
+// browsers are checked in order, the ones built on Chrome also claim to be Chrome and Safari.
+var browsers = []struct {
+	product string
+	name    string
+}{
+	{"Edg", "Edge"},
+	{"EdgA", "Edge"},
+	{"EdgiOS", "Edge"},
+	{"OPR", "Opera"},
+	{"SamsungBrowser", "Samsung Internet"},
+	{"Firefox", "Firefox"},
+	{"FxiOS", "Firefox"},
+	{"CriOS", "Chrome"},
+	{"Chromium", "Chromium"},
+	{"Chrome", "Chrome"},
+}
+
+type product struct {
+	name     string
+	version  string
+	comments []string
+}
+
+// Parse never returns nil, a header it can't make sense of gives KindUnknown.
+func Parse(userAgent string) *Client {
+	client := &Client{Kind: KindUnknown}
+
+	products := tokenize(userAgent)
+	if len(products) == 0 {
+		return client
+	}
+
+	first := products[0]
+	botName, botVersion, bot := findBot(products)
+
+	switch name := strings.ToLower(first.name); {
+	case name == "ccanalytics-go":
+		client.Kind = KindSDK
+		parseOwn(client, first)
+	case name == "ccanalytics-cli":
+		client.Kind = KindCLI
+		parseOwn(client, first)
+	case bot:
+		client.Kind = KindBot
+		client.Name, client.Version = botName, botVersion
+	case httpClients[name]:
+		client.Kind = KindHTTPClient
+		client.Name, client.Version = first.name, first.version
+	case name == "mozilla":
+		parseBrowser(client, products)
+	}
+
+	return client
+}
+
+// parseOwn reads the comment UserAgent in the client package writes, (command; goos/goarch; ci=name).
+func parseOwn(client *Client, p product) {
+	client.Name, client.Version = p.name, p.version
+
+	for _, comment := range p.comments {
+		if ci, ok := strings.CutPrefix(comment, "ci="); ok {
+			client.CI = ci
+			continue
+		}
+
+		if goos, _, ok := strings.Cut(comment, "/"); ok {
+			if name, known := goosNames[goos]; known {
+				client.OS = name
+				continue
+			}
+		}
+
+		if client.Kind == KindCLI && client.Command == "" {
+			client.Command = comment
+		}
+	}
+}
+
+func parseBrowser(client *Client, products []product) {
+	platform := strings.Join(products[0].comments, "; ")
+
+	client.OS = browserOS(platform)
+
+	for _, p := range products {
+		if p.name == "WindowsPowerShell" || p.name == "PowerShell" {
+			client.Kind = KindHTTPClient
+			client.Name, client.Version = p.name, p.version
+			return
+		}
+	}
+
+	client.Kind = KindBrowser
+
+	byName := make(map[string]product, len(products))
+	for _, p := range products {
+		byName[p.name] = p
+	}
+
+	for _, b := range browsers {
+		if p, ok := byName[b.product]; ok {
+			client.Name, client.Version = b.name, p.version
+			break
+		}
+	}
+
+	if _, ok := byName["Safari"]; ok && client.Name == "" {
+		client.Name, client.Version = "Safari", byName["Version"].version
+	}
+
+	_, mobile := byName["Mobile"]
+
+	switch {
+	case strings.Contains(platform, "iPad") || strings.Contains(platform, "Tablet"):
+		client.Device = DeviceTablet
+	case strings.Contains(platform, "Android") && !mobile && !strings.Contains(platform, "Mobile"):
+		client.Device = DeviceTablet
+	case mobile || strings.Contains(platform, "iPhone") || strings.Contains(platform, "Mobile"):
+		client.Device = DeviceMobile
+	default:
+		client.Device = DeviceDesktop
+	}
+}
+
+// browserOS reads the platform comment, the phones come first since they claim to be "like Mac OS X" or Linux.
+func browserOS(platform string) string {
+	switch {
+	case strings.Contains(platform, "iPhone") || strings.Contains(platform, "iPod"):
+		return "iOS"
+	case strings.Contains(platform, "iPad"):
+		return "iPadOS"
+	case strings.Contains(platform, "Android"):
+		return "Android"
+	case strings.Contains(platform, "Windows"):
+		return "Windows"
+	case strings.Contains(platform, "CrOS"):
+		return "ChromeOS"
+	case strings.Contains(platform, "Mac OS X") || strings.Contains(platform, "Macintosh"):
+		return "macOS"
+	case strings.Contains(platform, "Linux"):
+		return "Linux"
+	default:
+		return ""
+	}
+}
+
+// findBot looks for the product naming the bot, crawlers posing as browsers name themselves in a comment marked
+// compatible instead, (compatible; Googlebot/2.1; +http://www.google.com/bot.html). Bot words anywhere else don't
+// count, phone models such as CUBOT show up in the comments of plain browsers.
+func findBot(products []product) (string, string, bool) {
+	for _, p := range products {
+		if isBot(p.name) {
+			return p.name, p.version, true
+		}
+
+		if !slices.ContainsFunc(p.comments, func(comment string) bool { return strings.EqualFold(comment, "compatible") }) {
+			continue
+		}
+
+		for _, comment := range p.comments {
+			if name, version, _ := strings.Cut(comment, "/"); isBot(name) && !strings.Contains(name, " ") {
+				return name, version, true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+// isBot tells whether a product name is one of a bot, e.g. Googlebot, bingbot or Slackbot-LinkExpanding.
+func isBot(name string) bool {
+	lower := strings.ToLower(name)
+
+	return strings.Contains(lower, "bot") || strings.Contains(lower, "crawler") || strings.Contains(lower, "spider")
+}
+
+// tokenize splits the header into products, name/version, each with the comment in parentheses that follows it
+// split on semicolons.
+func tokenize(userAgent string) []product {
+	var products []product
+
+	rest := strings.TrimSpace(userAgent)
+	for rest != "" {
+		if rest[0] == '(' {
+			end := strings.IndexByte(rest, ')')
+			if end < 0 {
+				end = len(rest)
+			}
+
+			if len(products) > 0 {
+				last := &products[len(products)-1]
+				for comment := range strings.SplitSeq(rest[1:end], ";") {
+					if comment = strings.TrimSpace(comment); comment != "" {
+						last.comments = append(last.comments, comment)
+					}
+				}
+			}
+
+			rest = strings.TrimSpace(rest[min(end+1, len(rest)):])
+			continue
+		}
+
+		end := strings.IndexAny(rest, " (")
+		if end < 0 {
+			end = len(rest)
+		}
+
+		name, version, _ := strings.Cut(rest[:end], "/")
+		products = append(products, product{name: name, version: version})
+
+		rest = strings.TrimSpace(rest[end:])
+	}
+
+	return products
+}
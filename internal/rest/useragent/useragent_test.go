@@ -0,0 +1,95 @@
+package useragent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldParseUserAgents(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		expected  Client
+	}{
+		{
+			userAgent: "",
+			expected:  Client{Kind: KindUnknown},
+		},
+		{
+			userAgent: "ccanalytics-go/0.1.0 (darwin/arm64)",
+			expected:  Client{Kind: KindSDK, Name: "ccanalytics-go", Version: "0.1.0", OS: "macOS"},
+		},
+		{
+			userAgent: "ccanalytics-cli/0.1.0 (checksign; linux/amd64; ci=gitlab-ci)",
+			expected:  Client{Kind: KindCLI, Name: "ccanalytics-cli", Version: "0.1.0", OS: "Linux", Command: "checksign", CI: "gitlab-ci"},
+		},
+		{
+			// before the platform was sent
+			userAgent: "ccanalytics-cli/0.1.0 (signer)",
+			expected:  Client{Kind: KindCLI, Name: "ccanalytics-cli", Version: "0.1.0", Command: "signer"},
+		},
+		{
+			userAgent: "curl/8.5.0",
+			expected:  Client{Kind: KindHTTPClient, Name: "curl", Version: "8.5.0"},
+		},
+		{
+			userAgent: "Go-http-client/2.0",
+			expected:  Client{Kind: KindHTTPClient, Name: "Go-http-client", Version: "2.0"},
+		},
+		{
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Microsoft Windows 10.0.22631; en-US) PowerShell/7.4.1",
+			expected:  Client{Kind: KindHTTPClient, Name: "PowerShell", Version: "7.4.1", OS: "Windows"},
+		},
+		{
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			expected:  Client{Kind: KindBrowser, Name: "Edge", Version: "120.0.2210.91", OS: "Windows", Device: DeviceDesktop},
+		},
+		{
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+			expected:  Client{Kind: KindBrowser, Name: "Safari", Version: "17.2", OS: "macOS", Device: DeviceDesktop},
+		},
+		{
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			expected:  Client{Kind: KindBrowser, Name: "Safari", Version: "17.2", OS: "iOS", Device: DeviceMobile},
+		},
+		{
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Mobile Safari/537.36",
+			expected:  Client{Kind: KindBrowser, Name: "Chrome", Version: "120.0.6099.144", OS: "Android", Device: DeviceMobile},
+		},
+		{
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Safari/537.36",
+			expected:  Client{Kind: KindBrowser, Name: "Chrome", Version: "120.0.6099.144", OS: "Android", Device: DeviceTablet},
+		},
+		{
+			userAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			expected:  Client{Kind: KindBrowser, Name: "Firefox", Version: "121.0", OS: "Linux", Device: DeviceDesktop},
+		},
+		{
+			userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			expected:  Client{Kind: KindBot, Name: "Googlebot", Version: "2.1"},
+		},
+		{
+			userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			expected:  Client{Kind: KindBot, Name: "Slackbot-LinkExpanding"},
+		},
+		{
+			userAgent: "Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm) Chrome/120.0.6099.144 Safari/537.36",
+			expected:  Client{Kind: KindBot, Name: "bingbot", Version: "2.0"},
+		},
+		{
+			// the bot in the model name is not a crawler
+			userAgent: "Mozilla/5.0 (Linux; Android 12; CUBOT KingKong 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Mobile Safari/537.36",
+			expected:  Client{Kind: KindBrowser, Name: "Chrome", Version: "120.0.6099.144", OS: "Android", Device: DeviceMobile},
+		},
+		{
+			userAgent: "something we have never seen",
+			expected:  Client{Kind: KindUnknown},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userAgent, func(t *testing.T) {
+			require.Equal(t, &tt.expected, Parse(tt.userAgent))
+		})
+	}
+}
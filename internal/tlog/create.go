@@ -0,0 +1,42 @@
+package tlog
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
+
+const (
+	insertLeafQuery = "insert into tlog_leaves (leaf_index, hash, key_id, timestamp, leaf_hash, created_at, updated_at) values (?, ?, ?, ?, ?, ?, ?);"
+)
+
+// Append adds a leaf at the end of the log. The log is append-only, there is no way to update or remove a leaf.
+func Append(ctx context.Context, l *Leaf) (*Leaf, error) {
+	// the size read and the insert must not interleave with another append, or both would claim the same index
+	err := database.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := database.LockTable(ctx, "tlog_leaves"); err != nil {
+			return err
+		}
+
+		size, err := Size(ctx)
+		if err != nil {
+			return err
+		}
+
+		l.LeafIndex = size
+		l.LeafHash = hex.EncodeToString(LeafHash(l.Data().Bytes()))
+
+		if err := database.InsertContext(ctx, insertLeafQuery, l); err != nil {
+			return fmt.Errorf("appending leaf for %s: %w", l.Hash, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
@@ -0,0 +1,79 @@
+package tlog
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Leaf struct {
+	ID        int    `sql:"id"`
+	LeafIndex int    `sql:"leaf_index"`
+	Hash      string `sql:"hash"`
+	KeyID     string `sql:"key_id"`
+	Timestamp int64  `sql:"timestamp"`
+	LeafHash  string `sql:"leaf_hash"`
+	CreatedAt int64  `sql:"created_at"`
+	UpdatedAt int64  `sql:"updated_at"`
+}
+
+func (l *Leaf) GetID() int {
+	return l.ID
+}
+
+func (l *Leaf) SetID(id int) {
+	l.ID = id
+}
+
+// LeafData is the canonical encoding of a signing event, its RFC 6962 leaf hash is what goes into the tree.
+type LeafData struct {
+	Hash      string `json:"hash"`
+	KeyID     string `json:"key_id"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func (d LeafData) Bytes() []byte {
+	// Marshalling a struct of strings and integers cannot fail
+	data, _ := json.Marshal(d)
+	return data
+}
+
+type LeafOptions func(*Leaf)
+
+func WithHash(hash string) LeafOptions {
+	return func(l *Leaf) {
+		l.Hash = hash
+	}
+}
+
+func WithKeyID(keyID string) LeafOptions {
+	return func(l *Leaf) {
+		l.KeyID = keyID
+	}
+}
+
+func WithTimestamp(timestamp int64) LeafOptions {
+	return func(l *Leaf) {
+		l.Timestamp = timestamp
+	}
+}
+
+func NewLeaf(opts ...LeafOptions) *Leaf {
+	l := &Leaf{}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	l.CreatedAt = time.Now().UnixNano()
+	l.UpdatedAt = time.Now().UnixNano()
+
+	return l
+}
+
+func (l *Leaf) Data() LeafData {
+	return LeafData{
+		Hash:      l.Hash,
+		KeyID:     l.KeyID,
+		Timestamp: l.Timestamp,
+	}
+}
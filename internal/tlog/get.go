@@ -0,0 +1,78 @@
+package tlog
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/Gustrb/ccanalytics/internal/infrastructure/database"
+)
+
+const (
+	getTreeSizeQuery    = "select count(*) as size from tlog_leaves;"
+	getLeavesQuery      = "select * from tlog_leaves where leaf_index < ? order by leaf_index;"
+	getLeafByHashQuery  = "select * from tlog_leaves where hash = ? order by leaf_index limit 1;"
+	getLeafByIndexQuery = "select * from tlog_leaves where leaf_index = ?;"
+)
+
+type treeSize struct {
+	Size int `sql:"size"`
+}
+
+func Size(ctx context.Context) (int, error) {
+	rows, err := database.SelectContext[treeSize](ctx, getTreeSizeQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	return rows[0].Size, nil
+}
+
+func GetLeafByHash(ctx context.Context, hash string) (*Leaf, error) {
+	return getLeaf(ctx, getLeafByHashQuery, hash)
+}
+
+func GetLeafByIndex(ctx context.Context, index int) (*Leaf, error) {
+	return getLeaf(ctx, getLeafByIndexQuery, index)
+}
+
+func getLeaf(ctx context.Context, query string, args ...any) (*Leaf, error) {
+	leaves, err := database.SelectContext[Leaf](ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(leaves) == 0 {
+		return nil, nil
+	}
+
+	return leaves[0], nil
+}
+
+// leafHashes loads the hashes of the first size leaves, in order, ready to be fed to the Merkle functions.
+func leafHashes(ctx context.Context, size int) ([][]byte, error) {
+	leaves, err := database.SelectContext[Leaf](ctx, getLeavesQuery, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(leaves) != size {
+		return nil, fmt.Errorf("expected %d leaves, found %d: %w", size, len(leaves), ErrInvalidTreeSize)
+	}
+
+	hashes := make([][]byte, 0, size)
+	for _, l := range leaves {
+		h, err := hex.DecodeString(l.LeafHash)
+		if err != nil {
+			return nil, fmt.Errorf("decoding leaf %d: %w", l.LeafIndex, err)
+		}
+
+		hashes = append(hashes, h)
+	}
+
+	return hashes, nil
+}
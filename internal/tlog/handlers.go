@@ -0,0 +1,11 @@
+package tlog
+
+import (
+	"github.com/labstack/echo/v5"
+)
+
+func Urls(e *echo.Echo) {
+	e.GET("/tlog/sth", SignedTreeHeadHandler)
+	e.GET("/tlog/proof/inclusion", InclusionProofHandler)
+	e.GET("/tlog/proof/consistency", ConsistencyProofHandler)
+}
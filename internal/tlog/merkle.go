@@ -0,0 +1,228 @@
+package tlog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/bits"
+)
+
+// The tree follows RFC 6962 section 2.1, leaves and interior nodes are domain separated
+// so a leaf can never be passed off as an interior node.
+const (
+	leafHashPrefix = 0x00
+	nodeHashPrefix = 0x01
+)
+
+var (
+	ErrInvalidTreeSize    = errors.New("invalid tree size")
+	ErrInvalidLeafIndex   = errors.New("leaf index is out of range")
+	ErrInvalidProof       = errors.New("proof does not verify")
+	ErrInvalidProofLength = errors.New("proof has an unexpected length")
+)
+
+func LeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafHashPrefix})
+	h.Write(data)
+
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodeHashPrefix})
+	h.Write(left)
+	h.Write(right)
+
+	return h.Sum(nil)
+}
+
+// splitPoint returns the largest power of two strictly smaller than n.
+func splitPoint(n int) int {
+	return 1 << (bits.Len(uint(n-1)) - 1)
+}
+
+// RootHash computes MTH(D[n]) over already hashed leaves.
+func RootHash(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return leaves[0]
+	}
+
+	k := splitPoint(len(leaves))
+
+	return nodeHash(RootHash(leaves[:k]), RootHash(leaves[k:]))
+}
+
+// InclusionProof computes PATH(m, D[n]), the audit path proving leaves[index] is part of the tree.
+func InclusionProof(index int, leaves [][]byte) ([][]byte, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, ErrInvalidLeafIndex
+	}
+
+	return inclusionPath(index, leaves), nil
+}
+
+func inclusionPath(m int, leaves [][]byte) [][]byte {
+	if len(leaves) <= 1 {
+		return [][]byte{}
+	}
+
+	k := splitPoint(len(leaves))
+	if m < k {
+		return append(inclusionPath(m, leaves[:k]), RootHash(leaves[k:]))
+	}
+
+	return append(inclusionPath(m-k, leaves[k:]), RootHash(leaves[:k]))
+}
+
+// ConsistencyProof computes PROOF(m, D[n]), proving the tree of size m is a prefix of the tree built from leaves.
+func ConsistencyProof(m int, leaves [][]byte) ([][]byte, error) {
+	if m < 0 || m > len(leaves) {
+		return nil, ErrInvalidTreeSize
+	}
+
+	if m == 0 || m == len(leaves) {
+		return [][]byte{}, nil
+	}
+
+	return subProof(m, leaves, true), nil
+}
+
+func subProof(m int, leaves [][]byte, complete bool) [][]byte {
+	n := len(leaves)
+	if m == n {
+		if complete {
+			return [][]byte{}
+		}
+
+		return [][]byte{RootHash(leaves)}
+	}
+
+	k := splitPoint(n)
+	if m <= k {
+		return append(subProof(m, leaves[:k], complete), RootHash(leaves[k:]))
+	}
+
+	return append(subProof(m-k, leaves[k:], false), RootHash(leaves[:k]))
+}
+
+// VerifyInclusion checks an audit path as described in RFC 9162 section 2.1.3.2.
+func VerifyInclusion(index, treeSize int, leafHash []byte, proof [][]byte, root []byte) error {
+	if index < 0 || index >= treeSize {
+		return ErrInvalidLeafIndex
+	}
+
+	fn, sn := index, treeSize-1
+	r := leafHash
+
+	for _, p := range proof {
+		if sn == 0 {
+			return ErrInvalidProofLength
+		}
+
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+
+		fn >>= 1
+		sn >>= 1
+	}
+
+	if sn != 0 {
+		return ErrInvalidProofLength
+	}
+
+	if !bytes.Equal(r, root) {
+		return ErrInvalidProof
+	}
+
+	return nil
+}
+
+// VerifyConsistency checks a consistency proof as described in RFC 9162 section 2.1.4.2.
+func VerifyConsistency(first, second int, firstRoot, secondRoot []byte, proof [][]byte) error {
+	if first < 0 || first > second {
+		return ErrInvalidTreeSize
+	}
+
+	if first == second {
+		if len(proof) != 0 {
+			return ErrInvalidProofLength
+		}
+
+		if !bytes.Equal(firstRoot, secondRoot) {
+			return ErrInvalidProof
+		}
+
+		return nil
+	}
+
+	// Every tree is consistent with the empty tree
+	if first == 0 {
+		if len(proof) != 0 {
+			return ErrInvalidProofLength
+		}
+
+		return nil
+	}
+
+	if len(proof) == 0 {
+		return ErrInvalidProofLength
+	}
+
+	// When first is a power of two its root is part of the larger tree and is left out of the proof
+	if first&(first-1) == 0 {
+		proof = append([][]byte{firstRoot}, proof...)
+	}
+
+	fn, sn := first-1, second-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+
+	fr, sr := proof[0], proof[0]
+
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return ErrInvalidProofLength
+		}
+
+		if fn&1 == 1 || fn == sn {
+			fr = nodeHash(c, fr)
+			sr = nodeHash(c, sr)
+
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = nodeHash(sr, c)
+		}
+
+		fn >>= 1
+		sn >>= 1
+	}
+
+	if sn != 0 {
+		return ErrInvalidProofLength
+	}
+
+	if !bytes.Equal(fr, firstRoot) || !bytes.Equal(sr, secondRoot) {
+		return ErrInvalidProof
+	}
+
+	return nil
+}
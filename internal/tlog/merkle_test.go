@@ -0,0 +1,86 @@
+package tlog
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testLeaves(n int) [][]byte {
+	leaves := make([][]byte, n)
+	for i := range leaves {
+		leaves[i] = LeafHash(fmt.Appendf(nil, "leaf-%d", i))
+	}
+
+	return leaves
+}
+
+func TestShouldMatchRFC6962RootHashes(t *testing.T) {
+	// Test vectors from the certificate-transparency-go reference implementation
+	inputs := [][]byte{
+		{},
+		{0x00},
+		{0x10},
+		{0x20, 0x21},
+		{0x30, 0x31},
+		{0x40, 0x41, 0x42, 0x43},
+		{0x50, 0x51, 0x52, 0x53, 0x54, 0x55, 0x56, 0x57},
+		{0x60, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69, 0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f},
+	}
+
+	leaves := make([][]byte, 0, len(inputs))
+	for _, input := range inputs {
+		leaves = append(leaves, LeafHash(input))
+	}
+
+	require.Equal(t, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d", hex.EncodeToString(RootHash(leaves[:1])))
+	require.Equal(t, "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328", hex.EncodeToString(RootHash(leaves)))
+}
+
+func TestShouldVerifyInclusionProofs(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		leaves := testLeaves(n)
+		root := RootHash(leaves)
+
+		for i := range n {
+			proof, err := InclusionProof(i, leaves)
+			require.NoError(t, err)
+			require.NoError(t, VerifyInclusion(i, n, leaves[i], proof, root), "index %d of %d", i, n)
+
+			if n > 1 {
+				require.Error(t, VerifyInclusion(i, n, LeafHash([]byte("forged")), proof, root))
+			}
+		}
+	}
+}
+
+func TestShouldVerifyConsistencyProofs(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		leaves := testLeaves(n)
+		secondRoot := RootHash(leaves)
+
+		for m := 1; m <= n; m++ {
+			firstRoot := RootHash(leaves[:m])
+
+			proof, err := ConsistencyProof(m, leaves)
+			require.NoError(t, err)
+			require.NoError(t, VerifyConsistency(m, n, firstRoot, secondRoot, proof), "%d to %d", m, n)
+
+			if m < n {
+				require.Error(t, VerifyConsistency(m, n, LeafHash([]byte("forged")), secondRoot, proof))
+			}
+		}
+	}
+}
+
+func TestShouldRejectOutOfRangeIndexes(t *testing.T) {
+	leaves := testLeaves(4)
+
+	_, err := InclusionProof(4, leaves)
+	require.ErrorIs(t, err, ErrInvalidLeafIndex)
+
+	_, err = ConsistencyProof(5, leaves)
+	require.ErrorIs(t, err, ErrInvalidTreeSize)
+}
@@ -0,0 +1,155 @@
+package tlog
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/Gustrb/ccanalytics/internal/keys"
+)
+
+var (
+	ErrLeafNotFound = errors.New("no leaf found for hash")
+)
+
+type TreeHead struct {
+	TreeSize  int    `json:"tree_size"`
+	RootHash  string `json:"root_hash"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type InclusionProofResult struct {
+	LeafIndex int      `json:"leaf_index"`
+	TreeSize  int      `json:"tree_size"`
+	LeafHash  string   `json:"leaf_hash"`
+	Leaf      LeafData `json:"leaf"`
+	AuditPath []string `json:"audit_path"`
+	RootHash  string   `json:"root_hash"`
+}
+
+type ConsistencyProofResult struct {
+	FirstSize  int      `json:"first_size"`
+	SecondSize int      `json:"second_size"`
+	FirstRoot  string   `json:"first_root"`
+	SecondRoot string   `json:"second_root"`
+	Proof      []string `json:"proof"`
+}
+
+// SignedTreeHead seals the current size and root of the log with the active key, verifiers open it against the
+// keys they pinned, see keys.Envelope.Open.
+func SignedTreeHead(ctx context.Context) (*keys.Envelope, error) {
+	size, err := Size(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes, err := leafHashes(ctx, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys.Seal(ctx, TreeHead{
+		TreeSize:  size,
+		RootHash:  hex.EncodeToString(RootHash(hashes)),
+		Timestamp: time.Now().UnixNano(),
+	})
+}
+
+// ProveInclusion returns the audit path of the first leaf logged for hash in the tree of the given size,
+// a treeSize of 0 means the current tree.
+func ProveInclusion(ctx context.Context, hash string, treeSize int) (*InclusionProofResult, error) {
+	leaf, err := GetLeafByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if leaf == nil {
+		return nil, ErrLeafNotFound
+	}
+
+	treeSize, err = resolveTreeSize(ctx, treeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if leaf.LeafIndex >= treeSize {
+		return nil, ErrInvalidLeafIndex
+	}
+
+	hashes, err := leafHashes(ctx, treeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := InclusionProof(leaf.LeafIndex, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InclusionProofResult{
+		LeafIndex: leaf.LeafIndex,
+		TreeSize:  treeSize,
+		LeafHash:  leaf.LeafHash,
+		Leaf:      leaf.Data(),
+		AuditPath: encodeHashes(path),
+		RootHash:  hex.EncodeToString(RootHash(hashes)),
+	}, nil
+}
+
+// ProveConsistency proves the tree of size first is a prefix of the tree of size second,
+// a second of 0 means the current tree.
+func ProveConsistency(ctx context.Context, first, second int) (*ConsistencyProofResult, error) {
+	second, err := resolveTreeSize(ctx, second)
+	if err != nil {
+		return nil, err
+	}
+
+	if first < 0 || first > second {
+		return nil, ErrInvalidTreeSize
+	}
+
+	hashes, err := leafHashes(ctx, second)
+	if err != nil {
+		return nil, err
+	}
+
+	proof, err := ConsistencyProof(first, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConsistencyProofResult{
+		FirstSize:  first,
+		SecondSize: second,
+		FirstRoot:  hex.EncodeToString(RootHash(hashes[:first])),
+		SecondRoot: hex.EncodeToString(RootHash(hashes)),
+		Proof:      encodeHashes(proof),
+	}, nil
+}
+
+func resolveTreeSize(ctx context.Context, treeSize int) (int, error) {
+	size, err := Size(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if treeSize == 0 {
+		return size, nil
+	}
+
+	if treeSize < 0 || treeSize > size {
+		return 0, ErrInvalidTreeSize
+	}
+
+	return treeSize, nil
+}
+
+func encodeHashes(hashes [][]byte) []string {
+	encoded := make([]string, 0, len(hashes))
+	for _, h := range hashes {
+		encoded = append(encoded, hex.EncodeToString(h))
+	}
+
+	return encoded
+}
@@ -0,0 +1,78 @@
+package tlog
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+)
+
+func SignedTreeHeadHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	envelope, err := SignedTreeHead(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to build signed tree head", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to build signed tree head")
+	}
+
+	return c.JSON(http.StatusOK, envelope)
+}
+
+func InclusionProofHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	// leaves are logged under the lowercase hash
+	hash := strings.ToLower(c.QueryParam("hash"))
+	if hash == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "hash is required")
+	}
+
+	treeSize, err := echo.QueryParamOr(c, "tree_size", 0)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "tree_size must be an integer")
+	}
+
+	proof, err := ProveInclusion(ctx, hash, treeSize)
+	if err != nil {
+		return proofError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, proof)
+}
+
+func ConsistencyProofHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	first, err := echo.QueryParam[int](c, "first")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "first must be an integer")
+	}
+
+	second, err := echo.QueryParamOr(c, "second", 0)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "second must be an integer")
+	}
+
+	proof, err := ProveConsistency(ctx, first, second)
+	if err != nil {
+		return proofError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, proof)
+}
+
+func proofError(c *echo.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrLeafNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "hash is not in the log")
+	case errors.Is(err, ErrInvalidTreeSize), errors.Is(err, ErrInvalidLeafIndex):
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid tree size")
+	}
+
+	slog.ErrorContext(c.Request().Context(), "failed to build proof", "error", err)
+
+	return echo.NewHTTPError(http.StatusInternalServerError, "failed to build proof")
+}